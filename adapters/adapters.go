@@ -0,0 +1,224 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit/conditions"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+type (
+	// Adapter holds the objects needed to reconcile a resource of type T and implements the operations common to
+	// every kind. The adapter of each kind embeds it and only adds its domain operations. The Loader of type L is only
+	// held for them, so any loader can be used.
+	Adapter[T client.Object, L any] struct {
+		Client  client.Client
+		Context context.Context
+		Loader  L
+		Logger  *logr.Logger
+		Object  T
+	}
+
+	// Conditioned is implemented by the resources whose status contains conditions.
+	Conditioned interface {
+		// GetConditions returns a pointer to the conditions of the resource, so they can be modified
+		GetConditions() *[]metav1.Condition
+	}
+
+	// ReconcileRequester is implemented by the resources reporting in their status the last reconcile requested with an
+	// annotation they handled.
+	ReconcileRequester interface {
+		// GetLastHandledReconcileAt returns a pointer to the last reconcile request handled, so it can be modified
+		GetLastHandledReconcileAt() *string
+	}
+
+	// Finalizer is a finalizer along with the cleanup run before removing it from a resource being deleted.
+	Finalizer struct {
+		// Name is the name of the finalizer
+		Name string
+
+		// Finalize runs the cleanup and returns true once it is finished, so the finalizer can be removed. If it
+		// isn't, the resource is requeued after the returned delay or, if zero, when it changes again
+		Finalize func() (bool, time.Duration, error)
+	}
+)
+
+// New creates and returns an Adapter for the given resource.
+func New[T client.Object, L any](ctx context.Context, client client.Client, object T, loader L,
+	logger *logr.Logger) Adapter[T, L] {
+	return Adapter[T, L]{
+		Client:  client,
+		Context: ctx,
+		Loader:  loader,
+		Logger:  logger,
+		Object:  object,
+	}
+}
+
+// AddFinalizers adds the given finalizers to the resource if it doesn't contain them yet. It can be returned from an
+// operation, as it continues processing unless the resource can't be patched.
+func (a *Adapter[T, L]) AddFinalizers(finalizerNames ...string) (controller.OperationResult, error) {
+	var missing []string
+	for _, finalizerName := range finalizerNames {
+		if !controllerutil.ContainsFinalizer(a.Object, finalizerName) {
+			missing = append(missing, finalizerName)
+		}
+	}
+
+	if len(missing) == 0 {
+		return controller.ContinueProcessing()
+	}
+
+	a.Logger.Info("Adding finalizers", "Finalizers", missing)
+	return controller.RequeueOnErrorOrContinue(a.Patch(func() {
+		for _, finalizerName := range missing {
+			controllerutil.AddFinalizer(a.Object, finalizerName)
+		}
+	}))
+}
+
+// RunFinalizers runs the given finalizers, in order, if the resource is being deleted and removes each one once its
+// cleanup is finished. Finalizers not contained in the resource are skipped. It can be returned from an operation, as
+// it continues processing if the resource is not being deleted and stops it otherwise, requeueing the resource while
+// a cleanup is not finished or fails.
+func (a *Adapter[T, L]) RunFinalizers(finalizers ...Finalizer) (controller.OperationResult, error) {
+	if a.Object.GetDeletionTimestamp() == nil {
+		return controller.ContinueProcessing()
+	}
+
+	for _, finalizer := range finalizers {
+		if !controllerutil.ContainsFinalizer(a.Object, finalizer.Name) {
+			continue
+		}
+
+		finished, requeueDelay, err := finalizer.Finalize()
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+		if !finished {
+			if requeueDelay > 0 {
+				return controller.RequeueAfter(requeueDelay, nil)
+			}
+
+			return controller.StopProcessing()
+		}
+
+		err = a.Patch(func() {
+			controllerutil.RemoveFinalizer(a.Object, finalizer.Name)
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return controller.RequeueWithError(err)
+		}
+	}
+
+	return controller.StopProcessing()
+}
+
+// SetControllerReference sets the given owner as the controller of the resource unless it already is.
+func (a *Adapter[T, L]) SetControllerReference(owner client.Object) error {
+	if metav1.IsControlledBy(a.Object, owner) {
+		return nil
+	}
+
+	var err error
+	patchErr := a.Patch(func() {
+		err = ctrl.SetControllerReference(owner, a.Object, a.Client.Scheme())
+	})
+	if err != nil {
+		return err
+	}
+	if errors.IsNotFound(patchErr) {
+		return nil
+	}
+
+	return patchErr
+}
+
+// Patch applies the changes made by the given function to the metadata and spec of the resource.
+func (a *Adapter[T, L]) Patch(mutate func()) error {
+	patch := client.MergeFrom(a.Object.DeepCopyObject().(client.Object))
+	mutate()
+
+	return a.Client.Patch(a.Context, a.Object, patch)
+}
+
+// PatchStatus applies the changes made by the given function to the status of the resource. The patch fails if the
+// resource changed since it was loaded, in which case it is loaded again and the function applied again, so it
+// should only make changes based on the current state of the resource.
+func (a *Adapter[T, L]) PatchStatus(mutate func()) error {
+	first := true
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			err := a.Client.Get(a.Context, client.ObjectKeyFromObject(a.Object), a.Object)
+			if err != nil {
+				return err
+			}
+		}
+		first = false
+
+		patch := client.MergeFromWithOptions(a.Object.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+		mutate()
+
+		return a.Client.Status().Patch(a.Context, a.Object, patch)
+	})
+}
+
+// MarkReconcileRequestHandled reports the value of the given annotation of the resource, which requests a reconcile
+// whenever it changes, in its status, unless it is already reported, so whoever requested the reconcile can tell it
+// has been handled. The resource has to implement ReconcileRequester. It can be returned from an operation, as it
+// continues processing unless the status can't be patched.
+func (a *Adapter[T, L]) MarkReconcileRequestHandled(annotation string) (controller.OperationResult, error) {
+	requester, ok := any(a.Object).(ReconcileRequester)
+	if !ok {
+		return controller.RequeueWithError(fmt.Errorf("%T doesn't report the reconcile requests", a.Object))
+	}
+
+	requestedAt := a.Object.GetAnnotations()[annotation]
+	if requestedAt == "" || requestedAt == *requester.GetLastHandledReconcileAt() {
+		return controller.ContinueProcessing()
+	}
+
+	return controller.RequeueOnErrorOrContinue(a.PatchStatus(func() {
+		*requester.GetLastHandledReconcileAt() = requestedAt
+	}))
+}
+
+// MarkCondition sets the given condition in the status of the resource and patches it. The resource has to implement
+// Conditioned.
+func (a *Adapter[T, L]) MarkCondition(conditionType conditions.ConditionType, status metav1.ConditionStatus,
+	reason conditions.ConditionReason, message string) error {
+	conditioned, ok := any(a.Object).(Conditioned)
+	if !ok {
+		return fmt.Errorf("%T doesn't have conditions", a.Object)
+	}
+
+	return a.PatchStatus(func() {
+		conditions.SetConditionWithMessage(conditioned.GetConditions(), conditionType, status, reason, message)
+	})
+}
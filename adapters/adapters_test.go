@@ -0,0 +1,205 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Adapter", func() {
+	var (
+		foo *v1alpha1.Foo
+		h   *harness.Harness
+	)
+
+	newAdapter := func(obj client.Object) Adapter[client.Object, loader.ObjectLoader] {
+		h.Reload(obj)
+		return New[client.Object](h.Context, h.Client, obj, loader.NewLoader(), h.Logger)
+	}
+
+	BeforeEach(func() {
+		foo = harness.NewFoo("foo", "default").WithFinalizers("example.com/first", "example.com/second").Build()
+		h = harness.New(foo)
+	})
+
+	It("adds the missing finalizers only", func() {
+		adapter := newAdapter(foo)
+
+		result, err := adapter.AddFinalizers("example.com/first", "example.com/third")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.CancelRequest).To(BeFalse())
+
+		h.Reload(foo)
+		Expect(foo.Finalizers).To(Equal([]string{"example.com/first", "example.com/second", "example.com/third"}))
+	})
+
+	It("doesn't run the finalizers of resources not being deleted", func() {
+		adapter := newAdapter(foo)
+
+		result, err := adapter.RunFinalizers(Finalizer{
+			Name: "example.com/first",
+			Finalize: func() (bool, time.Duration, error) {
+				Fail("finalizer called")
+				return true, 0, nil
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.CancelRequest).To(BeFalse())
+		Expect(result.RequeueRequest).To(BeFalse())
+	})
+
+	When("the resource is being deleted", func() {
+		BeforeEach(func() {
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+		})
+
+		It("removes the finalizers in order once finished", func() {
+			adapter := newAdapter(foo)
+
+			var called []string
+			newFinalizer := func(name string) Finalizer {
+				return Finalizer{
+					Name: name,
+					Finalize: func() (bool, time.Duration, error) {
+						called = append(called, name)
+						return true, 0, nil
+					},
+				}
+			}
+
+			result, err := adapter.RunFinalizers(newFinalizer("example.com/third"), newFinalizer("example.com/second"),
+				newFinalizer("example.com/first"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.CancelRequest).To(BeTrue())
+			Expect(called).To(Equal([]string{"example.com/second", "example.com/first"}))
+			Expect(foo.Finalizers).To(BeEmpty())
+		})
+
+		It("keeps the finalizer and requeues the resource while the cleanup is not finished", func() {
+			adapter := newAdapter(foo)
+
+			result, err := adapter.RunFinalizers(Finalizer{
+				Name: "example.com/first",
+				Finalize: func() (bool, time.Duration, error) {
+					return false, time.Minute, nil
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueRequest).To(BeTrue())
+			Expect(result.RequeueDelay).To(Equal(time.Minute))
+
+			h.Reload(foo)
+			Expect(foo.Finalizers).To(ContainElement("example.com/first"))
+		})
+
+		It("keeps the finalizer and requeues the resource when the cleanup fails", func() {
+			adapter := newAdapter(foo)
+
+			result, err := adapter.RunFinalizers(Finalizer{
+				Name: "example.com/first",
+				Finalize: func() (bool, time.Duration, error) {
+					return false, 0, fmt.Errorf("cleanup failed")
+				},
+			})
+			Expect(err).To(MatchError("cleanup failed"))
+			Expect(result.RequeueRequest).To(BeTrue())
+
+			h.Reload(foo)
+			Expect(foo.Finalizers).To(ContainElement("example.com/first"))
+		})
+	})
+
+	It("sets the controller reference", func() {
+		bar := harness.NewBar("bar", "default").Build()
+		Expect(h.Client.Create(h.Context, bar)).To(Succeed())
+		h.Reload(foo)
+		adapter := newAdapter(bar)
+
+		Expect(adapter.SetControllerReference(foo)).To(Succeed())
+
+		h.Reload(bar)
+		Expect(metav1.IsControlledBy(bar, foo)).To(BeTrue())
+	})
+
+	It("patches the status of resources changed since they were loaded", func() {
+		adapter := newAdapter(foo)
+
+		stale := foo.DeepCopy()
+		stale.Spec.DesiredReplicas = 3
+		Expect(h.Client.Update(h.Context, stale)).To(Succeed())
+
+		Expect(adapter.PatchStatus(func() {
+			foo.Status.Replicas = []string{"bar"}
+		})).To(Succeed())
+
+		h.Reload(foo)
+		Expect(foo.Spec.DesiredReplicas).To(Equal(3))
+		Expect(foo.Status.Replicas).To(Equal([]string{"bar"}))
+	})
+
+	It("marks conditions on resources with conditions only", func() {
+		adapter := newAdapter(foo)
+		Expect(adapter.MarkCondition("Ready", metav1.ConditionTrue, "Tested", "message")).To(Succeed())
+
+		h.Reload(foo)
+		condition := meta.FindStatusCondition(foo.Status.Conditions, "Ready")
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("Tested"))
+
+		quota := &v1alpha1.FooQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "default"}}
+		Expect(h.Client.Create(h.Context, quota)).To(Succeed())
+		adapter = newAdapter(quota)
+		Expect(adapter.MarkCondition("Ready", metav1.ConditionTrue, "Tested", "")).NotTo(Succeed())
+	})
+
+	It("reports the reconcile requests once", func() {
+		foo.Annotations = map[string]string{v1alpha1.ReconcileRequestedAtAnnotation: "2023-05-04T10:00:00Z"}
+		Expect(h.Client.Update(h.Context, foo)).To(Succeed())
+
+		adapter := newAdapter(foo)
+		result, err := adapter.MarkReconcileRequestHandled(v1alpha1.ReconcileRequestedAtAnnotation)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.CancelRequest).To(BeFalse())
+
+		h.Reload(foo)
+		Expect(foo.Status.LastHandledReconcileAt).To(Equal("2023-05-04T10:00:00Z"))
+		resourceVersion := foo.ResourceVersion
+
+		adapter = newAdapter(foo)
+		_, err = adapter.MarkReconcileRequestHandled(v1alpha1.ReconcileRequestedAtAnnotation)
+		Expect(err).NotTo(HaveOccurred())
+		h.Reload(foo)
+		Expect(foo.ResourceVersion).To(Equal(resourceVersion))
+
+		quota := &v1alpha1.FooQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "default"}}
+		Expect(h.Client.Create(h.Context, quota)).To(Succeed())
+		adapter = newAdapter(quota)
+		_, err = adapter.MarkReconcileRequestHandled(v1alpha1.ReconcileRequestedAtAnnotation)
+		Expect(err).To(HaveOccurred())
+	})
+})
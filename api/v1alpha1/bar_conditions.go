@@ -0,0 +1,35 @@
+package v1alpha1
+
+import "github.com/konflux-ci/operator-toolkit/conditions"
+
+const (
+	// drainingConditionType is the type used to track whether a Bar resource has been asked to drain
+	drainingConditionType conditions.ConditionType = "Draining"
+
+	// orphanedConditionType is the type used to track whether the Foo resource of a Bar resource exists
+	orphanedConditionType conditions.ConditionType = "Orphaned"
+
+	// workloadReadyConditionType is the type used to track whether the workload of a Bar resource is ready
+	workloadReadyConditionType conditions.ConditionType = "WorkloadReady"
+)
+
+const (
+	// DrainRequestedReason is the reason set when the Bar resource is being removed and has to finish its work
+	DrainRequestedReason conditions.ConditionReason = "DrainRequested"
+
+	// FooFoundReason is the reason set when the Foo resource of the Bar resource exists
+	FooFoundReason conditions.ConditionReason = "FooFound"
+
+	// FooNotFoundReason is the reason set when the Foo resource of the Bar resource doesn't exist
+	FooNotFoundReason conditions.ConditionReason = "FooNotFound"
+
+	// WorkloadConflictReason is the reason set when a resource named after the Bar resource exists and is not
+	// controlled by it, so its workload can't be materialized
+	WorkloadConflictReason conditions.ConditionReason = "Conflict"
+
+	// WorkloadProgressingReason is the reason set when the workload is being rolled out or is unavailable
+	WorkloadProgressingReason conditions.ConditionReason = "Progressing"
+
+	// WorkloadReadyReason is the reason set when all the pods of the workload are updated and available
+	WorkloadReadyReason conditions.ConditionReason = "Ready"
+)
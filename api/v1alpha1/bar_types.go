@@ -17,7 +17,39 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit/conditions"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// FooLabel is the label used to store the name of the Foo resource a Bar resource belongs to
+	FooLabel = "appstudio.redhat.com/foo"
+
+	// FooUIDLabel is the label used to store the UID of the Foo resource owning a Bar resource. It is the only
+	// reference to the owner of the Bar resources in member clusters, as they can't be controlled by their Foo resource
+	FooUIDLabel = "appstudio.redhat.com/foo-uid"
+
+	// ManagedByLabel is the label used to store the name of the tool managing a Bar resource
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// ManagedByLabelValue is the value set in the ManagedByLabel for Bar resources managed by this operator
+	ManagedByLabelValue = "operator-toolkit-example"
+
+	// BarLabel is the label used to store the name of the Bar resource a workload belongs to
+	BarLabel = "appstudio.redhat.com/bar"
+
+	// DrainedAnnotation is the annotation set to "true" by the worker behind a draining Bar resource once it has
+	// finished its in-flight work, so the Bar resource can be deleted before its drain deadline
+	DrainedAnnotation = "appstudio.redhat.com/drained"
+
+	// TemplateHashAnnotation is the annotation containing the hash of the Foo template a Bar replica was created or
+	// last corrected from
+	TemplateHashAnnotation = "appstudio.redhat.com/template-hash"
 )
 
 // BarSpec defines the desired state of Bar
@@ -25,16 +57,200 @@ type BarSpec struct {
 	// Foo is the name of the Foo resource associated with this resource
 	// +optional
 	Foo string `json:"foo,omitempty"`
+
+	// Workload describes the workload run by this resource. Nothing is run when not set
+	// +optional
+	Workload *BarWorkload `json:"workload,omitempty"`
 }
 
-//+kubebuilder:object:root=true
+// BarWorkload defines the workload of a Bar resource, made of a ConfigMap and a Deployment named after the Bar
+type BarWorkload struct {
+	// Config is the data of the ConfigMap. Pods can mount it or read it as environment variables using the name of
+	// the Bar resource. Changing it rolls out the Deployment
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+
+	// Replicas is the number of pods of the Deployment. Defaults to 1
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Template is the pod template of the Deployment. The labels selecting the pods of the Bar are added to it
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Template corev1.PodTemplateSpec `json:"template"`
+}
+
+// BarStatus defines the observed state of Bar
+type BarStatus struct {
+	// Conditions represent the latest available observations for the Bar resource
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// DrainDeadline is the time the Bar resource is deleted at if it hasn't acknowledged the drain request by then
+	// +optional
+	DrainDeadline *metav1.Time `json:"drainDeadline,omitempty"`
+
+	// LastHandledReconcileAt is the value of the reconcile.appstudio.redhat.com/requested-at annotation of the
+	// resource the last time it was reconciled
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
+	// Workload is the observed state of the workload of the Bar resource
+	// +optional
+	Workload *WorkloadStatus `json:"workload,omitempty"`
+}
+
+// WorkloadStatus defines the observed state of the workload of a Bar resource
+type WorkloadStatus struct {
+	// AvailableReplicas is the number of available pods of the Deployment
+	AvailableReplicas int32 `json:"availableReplicas"`
+
+	// Replicas is the desired number of pods of the Deployment
+	Replicas int32 `json:"replicas"`
+}
+
+// GetConditions returns a pointer to the conditions of the Bar resource
+func (b *Bar) GetConditions() *[]metav1.Condition {
+	return &b.Status.Conditions
+}
+
+// GetFooUID returns the UID of the Foo resource owning the Bar resource, which is the one in its FooUIDLabel or, if the
+// label isn't set, the one of the Foo resource controlling it. An empty UID is returned for Bar resources without
+// owner. Every Bar resource has a single owner, no matter which Foo resource its labels or spec refer to.
+func (b *Bar) GetFooUID() types.UID {
+	if uid, found := b.Labels[FooUIDLabel]; found {
+		return types.UID(uid)
+	}
+
+	if owner := metav1.GetControllerOf(b); owner != nil && owner.APIVersion == GroupVersion.String() &&
+		owner.Kind == "Foo" {
+		return owner.UID
+	}
+
+	return ""
+}
+
+// GetLastHandledReconcileAt returns a pointer to the last reconcile request handled for the Bar resource, so it can be
+// modified
+func (b *Bar) GetLastHandledReconcileAt() *string {
+	return &b.Status.LastHandledReconcileAt
+}
+
+// GetOrphanedTime returns the time the Bar resource was marked as orphaned. The zero time is returned if the Bar
+// resource is not orphaned.
+func (b *Bar) GetOrphanedTime() metav1.Time {
+	condition := meta.FindStatusCondition(b.Status.Conditions, orphanedConditionType.String())
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		return metav1.Time{}
+	}
+
+	return condition.LastTransitionTime
+}
+
+// IsDrained returns true if the Bar resource has acknowledged the drain request
+func (b *Bar) IsDrained() bool {
+	return b.Annotations[DrainedAnnotation] == "true"
+}
+
+// IsDraining returns true if the Bar resource has been asked to drain
+func (b *Bar) IsDraining() bool {
+	return meta.IsStatusConditionTrue(b.Status.Conditions, drainingConditionType.String())
+}
+
+// GetDrainTimeLeft returns the time left until the Bar resource is deleted without acknowledging the drain request.
+// Zero is returned if the Bar resource is drained or its drain deadline has been reached.
+func (b *Bar) GetDrainTimeLeft(now time.Time) time.Duration {
+	if b.IsDrained() || b.Status.DrainDeadline == nil || !now.Before(b.Status.DrainDeadline.Time) {
+		return 0
+	}
+
+	return b.Status.DrainDeadline.Sub(now)
+}
+
+// IsOrphaned returns true if the Bar resource has been marked as orphaned
+func (b *Bar) IsOrphaned() bool {
+	return meta.IsStatusConditionTrue(b.Status.Conditions, orphanedConditionType.String())
+}
+
+// GetReadyTransitionTime returns the last time the Bar resource became ready or stopped being ready. The creation
+// time is returned if its readiness never changed.
+func (b *Bar) GetReadyTransitionTime() metav1.Time {
+	condition := meta.FindStatusCondition(b.Status.Conditions, workloadReadyConditionType.String())
+	if b.Spec.Workload == nil || condition == nil {
+		return b.CreationTimestamp
+	}
+
+	return condition.LastTransitionTime
+}
+
+// IsReady returns true if the Bar resource has no workload or its workload is ready
+func (b *Bar) IsReady() bool {
+	return b.Spec.Workload == nil || b.IsWorkloadReady()
+}
+
+// IsWorkloadReady returns true if the workload of the Bar resource has been marked as ready
+func (b *Bar) IsWorkloadReady() bool {
+	return meta.IsStatusConditionTrue(b.Status.Conditions, workloadReadyConditionType.String())
+}
+
+// MarkAdopted marks the Bar resource as not orphaned, as its Foo resource exists
+func (b *Bar) MarkAdopted() {
+	conditions.SetCondition(&b.Status.Conditions, orphanedConditionType, metav1.ConditionFalse, FooFoundReason)
+}
+
+// MarkDraining marks the Bar resource as draining, setting the deadline for it to acknowledge the drain request
+func (b *Bar) MarkDraining(deadline time.Time) {
+	b.Status.DrainDeadline = &metav1.Time{Time: deadline}
+	conditions.SetConditionWithMessage(&b.Status.Conditions, drainingConditionType, metav1.ConditionTrue,
+		DrainRequestedReason, "Drain requested until "+deadline.UTC().Format(time.RFC3339))
+}
+
+// MarkOrphaned marks the Bar resource as orphaned, as its Foo resource doesn't exist
+func (b *Bar) MarkOrphaned() {
+	conditions.SetConditionWithMessage(&b.Status.Conditions, orphanedConditionType, metav1.ConditionTrue,
+		FooNotFoundReason, "Foo "+b.Spec.Foo+" not found")
+}
+
+// MarkWorkloadConflict marks the workload of the Bar resource as not ready, as one of its resources exists and is not
+// controlled by the Bar resource
+func (b *Bar) MarkWorkloadConflict(message string) {
+	conditions.SetConditionWithMessage(&b.Status.Conditions, workloadReadyConditionType, metav1.ConditionFalse,
+		WorkloadConflictReason, message)
+}
+
+// MarkWorkloadProgressing marks the workload of the Bar resource as not ready yet
+func (b *Bar) MarkWorkloadProgressing(message string) {
+	conditions.SetConditionWithMessage(&b.Status.Conditions, workloadReadyConditionType, metav1.ConditionFalse,
+		WorkloadProgressingReason, message)
+}
+
+// MarkWorkloadReady marks the workload of the Bar resource as ready
+func (b *Bar) MarkWorkloadReady() {
+	conditions.SetCondition(&b.Status.Conditions, workloadReadyConditionType, metav1.ConditionTrue, WorkloadReadyReason)
+}
+
+// RemoveWorkloadStatus removes the workload state from the status of a Bar resource without workload
+func (b *Bar) RemoveWorkloadStatus() {
+	meta.RemoveStatusCondition(&b.Status.Conditions, workloadReadyConditionType.String())
+	b.Status.Workload = nil
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Foo",type=string,JSONPath=`.spec.foo`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="WorkloadReady")].status`
+// +kubebuilder:printcolumn:name="Draining",type=string,JSONPath=`.status.conditions[?(@.type=="Draining")].status`
+// +kubebuilder:printcolumn:name="Orphaned",type=string,JSONPath=`.status.conditions[?(@.type=="Orphaned")].status`
 
 // Bar is the Schema for the bars API
 type Bar struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec BarSpec `json:"spec,omitempty"`
+	Spec   BarSpec   `json:"spec,omitempty"`
+	Status BarStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -3,17 +3,39 @@ package v1alpha1
 import "github.com/konflux-ci/operator-toolkit/conditions"
 
 const (
+	// driftDetectedConditionType is the type used to track whether the Bar resources of a Foo resource match it
+	driftDetectedConditionType conditions.ConditionType = "DriftDetected"
+
 	// healthConditionType is the type used to track the health of a Foo resource
 	healthConditionType conditions.ConditionType = "Health"
+
+	// terminatingConditionType is the type used to track the deletion of the Bar resources of a Foo resource being
+	// deleted
+	terminatingConditionType conditions.ConditionType = "Terminating"
 )
 
 const (
+	// BarsDriftedReason is the reason set when some Bar resources don't match the resource and are left as they are
+	BarsDriftedReason conditions.ConditionReason = "BarsDrifted"
+
+	// BarsRemainingReason is the reason set when the resource is being deleted and some of its Bar resources still exist
+	BarsRemainingReason conditions.ConditionReason = "BarsRemaining"
+
+	// DriftCorrectedReason is the reason set when the drifted Bar resources have been restored to match the resource
+	DriftCorrectedReason conditions.ConditionReason = "DriftCorrected"
+
 	// HealthyReason is the reason set when the resource is healthy
 	HealthyReason conditions.ConditionReason = "Healthy"
 
+	// NoDriftReason is the reason set when all the Bar resources match the resource
+	NoDriftReason conditions.ConditionReason = "NoDrift"
+
 	// NotEnoughReplicasReason is the reason set when the resource needs to scale up
 	NotEnoughReplicasReason conditions.ConditionReason = "NotEnoughReplicas"
 
+	// QuotaExceededReason is the reason set when the resource can't scale up due to a FooQuota
+	QuotaExceededReason conditions.ConditionReason = "QuotaExceeded"
+
 	// TooManyReplicasReason is the reason set when the resource needs to scale down
 	TooManyReplicasReason conditions.ConditionReason = "TooManyReplicas"
 )
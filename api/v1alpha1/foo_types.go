@@ -17,14 +17,73 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/konflux-ci/operator-toolkit/conditions"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultTerminationGracePeriodSeconds is the time Bar replicas are given to drain when the Foo resource doesn't set it
+const DefaultTerminationGracePeriodSeconds int64 = 30
+
+// ReconcileRequestedAtAnnotation is the annotation requesting a reconcile of the Foo or Bar resource it is set on
+// whenever its value changes, e.g. to the current time. The last value handled is reported in the status
+const ReconcileRequestedAtAnnotation = "reconcile.appstudio.redhat.com/requested-at"
+
+// HandlerFinalizersAnnotation is the annotation recording the comma-separated finalizers of finalizer handlers added to
+// the Foo resource by the operator, so they can be told apart from the finalizers added by others within the same
+// domain when their handler isn't registered anymore
+const HandlerFinalizersAnnotation = "appstudio.redhat.com/handler-finalizers"
+
+// DriftPolicy defines what happens to the Bar replicas which don't match their Foo resource anymore
+// +kubebuilder:validation:Enum=Correct;Report
+type DriftPolicy string
+
+const (
+	// DriftPolicyCorrect restores the drifted Bar replicas to match their Foo resource
+	DriftPolicyCorrect DriftPolicy = "Correct"
+
+	// DriftPolicyReport only reports the drifted Bar replicas in the DriftDetected condition
+	DriftPolicyReport DriftPolicy = "Report"
 )
 
 // FooSpec defines the desired state of Foo
 type FooSpec struct {
 	// DesiredReplicas is the number of Bar replicas that should exist at any given moment
 	DesiredReplicas int `json:"desiredReplicas"`
+
+	// DriftPolicy is what happens to the Bar replicas whose labels were changed so they don't match the Foo resource
+	// anymore. One of Correct or Report. Defaults to Report
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Placement defines the member clusters the Bar replicas are created in. Bar replicas are created in the same
+	// cluster as the Foo resource when not set
+	// +optional
+	Placement *FooPlacement `json:"placement,omitempty"`
+
+	// ResyncPeriod is how often the Foo resource is reconciled when nothing changes, so Bar replicas modified without
+	// triggering a reconcile are detected. The Foo resource is not resynced when not set
+	// +optional
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+
+	// TerminationGracePeriodSeconds is the time Bar replicas being removed are given to drain before being deleted
+	// without acknowledging it. Defaults to 30 seconds
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+}
+
+// FooPlacement defines the member clusters the Bar replicas of a Foo resource are placed into
+type FooPlacement struct {
+	// Clusters is the list of member clusters the Bar replicas are created in. Every member cluster gets the
+	// desired number of replicas
+	// +kubebuilder:validation:MinItems=1
+	Clusters []string `json:"clusters"`
 }
 
 // FooStatus defines the observed state of Foo
@@ -33,8 +92,238 @@ type FooStatus struct {
 	// +optional
 	Conditions []metav1.Condition `json:"conditions"`
 
-	// Replicas is a slice containing the list of replica names for this resource
+	// Replicas is a slice containing the list of replica names for this resource, limited to the replicas listed in
+	// ReplicaDetails
+	//
+	// Deprecated: use ReplicaDetails instead. This field will be removed in a future API version
+	Replicas []string `json:"replicas,omitempty"`
+
+	// ReplicaDetails is a slice containing the observed state of the replicas of this resource, sorted by name. Only
+	// the first replicas are listed when there are more than the maximum set in the operator configuration
+	// +optional
+	ReplicaDetails []ReplicaStatus `json:"replicaDetails,omitempty"`
+
+	// OmittedReplicas is the number of replicas left out of ReplicaDetails to keep the status small
+	// +optional
+	OmittedReplicas int `json:"omittedReplicas,omitempty"`
+
+	// TemplateRevision is the hash of the fields of the Bar replicas managed by this resource. Replicas with a
+	// different template revision have drifted
+	// +optional
+	TemplateRevision string `json:"templateRevision,omitempty"`
+
+	// Clusters is a slice containing the observed state of the replicas placed in each member cluster
+	// +optional
+	Clusters []ClusterStatus `json:"clusters,omitempty"`
+
+	// PlacedClusters is the sorted list of member clusters Bar replicas were created in. A member cluster is kept in
+	// the list until its Bar replicas are deleted, so they are cleaned up when it is removed from the placement
+	// +optional
+	PlacedClusters []string `json:"placedClusters,omitempty"`
+
+	// Finalizers is a slice containing the finalizer handlers which failed while deleting the resource
+	// +optional
+	Finalizers []FinalizerStatus `json:"finalizers,omitempty"`
+
+	// LastHandledReconcileAt is the value of the reconcile.appstudio.redhat.com/requested-at annotation of the
+	// resource the last time it was reconciled
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+}
+
+// ClusterStatus defines the observed state of the Bar replicas placed in a member cluster
+type ClusterStatus struct {
+	// Name is the name of the member cluster
+	Name string `json:"name"`
+
+	// Replicas is a slice containing the list of replica names in the member cluster, limited to the replicas listed
+	// in ReplicaDetails
+	//
+	// Deprecated: use ReplicaDetails instead. This field will be removed in a future API version
 	Replicas []string `json:"replicas,omitempty"`
+
+	// ReplicaDetails is a slice containing the observed state of the replicas in the member cluster, sorted by name
+	// and limited the same way as the replicas in the cluster of the Foo resource
+	// +optional
+	ReplicaDetails []ReplicaStatus `json:"replicaDetails,omitempty"`
+
+	// OmittedReplicas is the number of replicas left out of ReplicaDetails to keep the status small
+	// +optional
+	OmittedReplicas int `json:"omittedReplicas,omitempty"`
+}
+
+// ReplicaStatus defines the observed state of a Bar replica
+type ReplicaStatus struct {
+	// Name is the name of the Bar replica
+	Name string `json:"name"`
+
+	// UID is the UID of the Bar replica
+	UID types.UID `json:"uid"`
+
+	// CreationTimestamp is the time the Bar replica was created
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+
+	// TemplateRevision is the hash of the fields of the Bar replica managed by the Foo resource
+	TemplateRevision string `json:"templateRevision"`
+
+	// Ready is true if the Bar replica has no workload or its workload is ready
+	Ready bool `json:"ready"`
+
+	// LastTransitionTime is the last time the Bar replica became ready or stopped being ready
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// GetConditions returns a pointer to the conditions of the Foo resource
+func (f *Foo) GetConditions() *[]metav1.Condition {
+	return &f.Status.Conditions
+}
+
+// GetDriftPolicy returns the policy applied to the drifted Bar replicas of the Foo resource
+func (f *Foo) GetDriftPolicy() DriftPolicy {
+	if f.Spec.DriftPolicy == "" {
+		return DriftPolicyReport
+	}
+
+	return f.Spec.DriftPolicy
+}
+
+// GetHandlerFinalizers returns the finalizers of finalizer handlers recorded as added to the Foo resource
+func (f *Foo) GetHandlerFinalizers() []string {
+	recorded := f.Annotations[HandlerFinalizersAnnotation]
+	if recorded == "" {
+		return nil
+	}
+
+	return strings.Split(recorded, ",")
+}
+
+// RecordHandlerFinalizers records the given finalizers of finalizer handlers as added to the Foo resource. It returns
+// true if any of them wasn't recorded yet.
+func (f *Foo) RecordHandlerFinalizers(finalizers ...string) bool {
+	recorded := f.GetHandlerFinalizers()
+	changed := false
+	for _, finalizer := range finalizers {
+		index := sort.SearchStrings(recorded, finalizer)
+		if index < len(recorded) && recorded[index] == finalizer {
+			continue
+		}
+
+		recorded = append(recorded, "")
+		copy(recorded[index+1:], recorded[index:])
+		recorded[index] = finalizer
+		changed = true
+	}
+
+	if changed {
+		if f.Annotations == nil {
+			f.Annotations = map[string]string{}
+		}
+		f.Annotations[HandlerFinalizersAnnotation] = strings.Join(recorded, ",")
+	}
+
+	return changed
+}
+
+// GetLastHandledReconcileAt returns a pointer to the last reconcile request handled for the Foo resource, so it can be
+// modified
+func (f *Foo) GetLastHandledReconcileAt() *string {
+	return &f.Status.LastHandledReconcileAt
+}
+
+// GetTerminationGracePeriod returns the time the Bar replicas of the Foo resource are given to drain
+func (f *Foo) GetTerminationGracePeriod() time.Duration {
+	seconds := DefaultTerminationGracePeriodSeconds
+	if f.Spec.TerminationGracePeriodSeconds != nil {
+		seconds = *f.Spec.TerminationGracePeriodSeconds
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// AddPlacedCluster records the member cluster with the given name as one Bar replicas were created in. It returns
+// true if it wasn't recorded yet.
+func (f *Foo) AddPlacedCluster(name string) bool {
+	index := sort.SearchStrings(f.Status.PlacedClusters, name)
+	if index < len(f.Status.PlacedClusters) && f.Status.PlacedClusters[index] == name {
+		return false
+	}
+
+	f.Status.PlacedClusters = append(f.Status.PlacedClusters, "")
+	copy(f.Status.PlacedClusters[index+1:], f.Status.PlacedClusters[index:])
+	f.Status.PlacedClusters[index] = name
+
+	return true
+}
+
+// RemovePlacedCluster removes the member cluster with the given name from the ones Bar replicas were created in.
+func (f *Foo) RemovePlacedCluster(name string) {
+	for i, placedCluster := range f.Status.PlacedClusters {
+		if placedCluster == name {
+			f.Status.PlacedClusters = append(f.Status.PlacedClusters[:i], f.Status.PlacedClusters[i+1:]...)
+			return
+		}
+	}
+}
+
+// FinalizerStatus defines the observed state of a finalizer handler which failed while deleting a Foo resource
+type FinalizerStatus struct {
+	// Name is the name of the finalizer handler
+	Name string `json:"name"`
+
+	// Failures is the number of times the finalizer handler failed
+	Failures int `json:"failures"`
+
+	// LastError is the error returned by the last failed run of the finalizer handler
+	LastError string `json:"lastError"`
+
+	// LastFailureTime is the time the finalizer handler failed for the last time
+	LastFailureTime metav1.Time `json:"lastFailureTime"`
+}
+
+// MarkFinalizerFailed records the given error of the finalizer handler with the given name in the Foo resource status
+func (f *Foo) MarkFinalizerFailed(name string, err error) {
+	for i := range f.Status.Finalizers {
+		if f.Status.Finalizers[i].Name == name {
+			f.Status.Finalizers[i].Failures++
+			f.Status.Finalizers[i].LastError = err.Error()
+			f.Status.Finalizers[i].LastFailureTime = metav1.Now()
+			return
+		}
+	}
+
+	f.Status.Finalizers = append(f.Status.Finalizers, FinalizerStatus{
+		Name:            name,
+		Failures:        1,
+		LastError:       err.Error(),
+		LastFailureTime: metav1.Now(),
+	})
+}
+
+// RemoveFinalizerStatus removes the failures of the finalizer handler with the given name from the Foo resource
+// status. It returns true if there were any.
+func (f *Foo) RemoveFinalizerStatus(name string) bool {
+	for i := range f.Status.Finalizers {
+		if f.Status.Finalizers[i].Name == name {
+			f.Status.Finalizers = append(f.Status.Finalizers[:i], f.Status.Finalizers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// MarkDriftCorrected marks the Foo resource as not drifted after restoring the given drifted Bar resources
+func (f *Foo) MarkDriftCorrected(correctedBars []string) {
+	conditions.SetConditionWithMessage(&f.Status.Conditions, driftDetectedConditionType, metav1.ConditionFalse,
+		DriftCorrectedReason, fmt.Sprintf("Corrected %d Bar resources: %s", len(correctedBars),
+			strings.Join(correctedBars, ", ")))
+}
+
+// MarkDriftDetected marks the Foo resource as having the given drifted Bar resources
+func (f *Foo) MarkDriftDetected(driftedBars []string) {
+	conditions.SetConditionWithMessage(&f.Status.Conditions, driftDetectedConditionType, metav1.ConditionTrue,
+		BarsDriftedReason, fmt.Sprintf("%d Bar resources drifted: %s", len(driftedBars),
+			strings.Join(driftedBars, ", ")))
 }
 
 // MarkHealthy marks the Foo resource as healthy using the reason passed as a parameter
@@ -42,11 +331,28 @@ func (f *Foo) MarkHealthy(reason conditions.ConditionReason) {
 	conditions.SetCondition(&f.Status.Conditions, healthConditionType, metav1.ConditionTrue, reason)
 }
 
+// MarkTerminating marks the Foo resource as waiting for the given Bar resources to be deleted
+func (f *Foo) MarkTerminating(remainingBars []string) {
+	conditions.SetConditionWithMessage(&f.Status.Conditions, terminatingConditionType, metav1.ConditionTrue,
+		BarsRemainingReason, fmt.Sprintf("Waiting for %d Bar resources to be deleted: %s", len(remainingBars),
+			strings.Join(remainingBars, ", ")))
+}
+
+// MarkNoDrift marks the Foo resource as having no drifted Bar resources
+func (f *Foo) MarkNoDrift() {
+	conditions.SetCondition(&f.Status.Conditions, driftDetectedConditionType, metav1.ConditionFalse, NoDriftReason)
+}
+
 // MarkUnhealthy marks the Foo resource as unhealthy
 func (f *Foo) MarkUnhealthy() {
 	conditions.SetCondition(&f.Status.Conditions, healthConditionType, metav1.ConditionFalse, NotEnoughReplicasReason)
 }
 
+// MarkQuotaExceeded marks the Foo resource as unhealthy because a FooQuota prevents it from scaling up
+func (f *Foo) MarkQuotaExceeded() {
+	conditions.SetCondition(&f.Status.Conditions, healthConditionType, metav1.ConditionFalse, QuotaExceededReason)
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Health",type=string,JSONPath=`.status.conditions[?(@.type=="Health")].reason`
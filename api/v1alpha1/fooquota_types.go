@@ -0,0 +1,102 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FooQuotaSpec defines the desired state of FooQuota
+type FooQuotaSpec struct {
+	// MaxBars is the maximum number of Bar resources that can exist in the namespace
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxBars *int `json:"maxBars,omitempty"`
+
+	// MaxDesiredReplicas is the maximum number of desired replicas a single Foo resource can request
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDesiredReplicas *int `json:"maxDesiredReplicas,omitempty"`
+
+	// MaxFoos is the maximum number of Foo resources that can exist in the namespace
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxFoos *int `json:"maxFoos,omitempty"`
+}
+
+// FooQuotaStatus defines the observed state of FooQuota
+type FooQuotaStatus struct {
+	// Used is the current usage of the resources limited by this quota
+	// +optional
+	Used FooQuotaUsage `json:"used,omitempty"`
+}
+
+// FooQuotaUsage defines the number of resources in use in a namespace
+type FooQuotaUsage struct {
+	// Bars is the number of Bar resources in the namespace
+	Bars int `json:"bars"`
+
+	// Foos is the number of Foo resources in the namespace
+	Foos int `json:"foos"`
+}
+
+// ExceedsMaxBars returns true if the number of Bar resources passed as a parameter goes beyond the limit set in
+// the FooQuota resource.
+func (q *FooQuota) ExceedsMaxBars(bars int) bool {
+	return q.Spec.MaxBars != nil && bars > *q.Spec.MaxBars
+}
+
+// ExceedsMaxDesiredReplicas returns true if the number of replicas passed as a parameter goes beyond the limit set in
+// the FooQuota resource.
+func (q *FooQuota) ExceedsMaxDesiredReplicas(replicas int) bool {
+	return q.Spec.MaxDesiredReplicas != nil && replicas > *q.Spec.MaxDesiredReplicas
+}
+
+// ExceedsMaxFoos returns true if the number of Foo resources passed as a parameter goes beyond the limit set in
+// the FooQuota resource.
+func (q *FooQuota) ExceedsMaxFoos(foos int) bool {
+	return q.Spec.MaxFoos != nil && foos > *q.Spec.MaxFoos
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Foos",type=integer,JSONPath=`.status.used.foos`
+// +kubebuilder:printcolumn:name="Max Foos",type=integer,JSONPath=`.spec.maxFoos`
+// +kubebuilder:printcolumn:name="Bars",type=integer,JSONPath=`.status.used.bars`
+// +kubebuilder:printcolumn:name="Max Bars",type=integer,JSONPath=`.spec.maxBars`
+
+// FooQuota is the Schema for the fooquotas API
+type FooQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FooQuotaSpec   `json:"spec,omitempty"`
+	Status FooQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FooQuotaList contains a list of FooQuota
+type FooQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FooQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FooQuota{}, &FooQuotaList{})
+}
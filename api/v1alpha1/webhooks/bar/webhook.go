@@ -23,16 +23,31 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
 	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit/metadata"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Webhook describes the data structure for the bar webhook
 type Webhook struct {
-	client client.Client
-	loader loader.ObjectLoader
-	log    logr.Logger
+	client        client.Client
+	enforceQuotas bool
+	loader        loader.ObjectLoader
+	log           logr.Logger
+	namespaces    operatorconfig.NamespaceFilter
+}
+
+// Configure applies the watched namespaces and the FooQuotas feature gate set in the operator configuration. Bar
+// resources in other namespaces are admitted without changes and the FooQuota resources are only enforced when the
+// feature is enabled.
+func (w *Webhook) Configure(config *operatorconfig.OperatorConfig) {
+	w.enforceQuotas = config.IsFeatureEnabled(operatorconfig.FooQuotasFeature)
+	w.namespaces = config.GetNamespaceFilter()
 }
 
 // Register registers the webhook with the passed manager and log.
@@ -50,9 +65,52 @@ func (w *Webhook) Register(mgr ctrl.Manager, log *logr.Logger) error {
 
 // +kubebuilder:webhook:path=/mutate-appstudio-redhat-com-v1alpha1-bar,mutating=true,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=bars,verbs=create;update,versions=v1alpha1,name=mbar.kb.io,admissionReviewVersions=v1
 
-// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// Default implements webhook.Defaulter so a webhook will be registered for the type. It stamps the standard labels
+// on the Bar resource and sets the referenced Foo resource as its controller, so the Bar is owned from the moment
+// it gets created. When spec.foo changes, the controller reference to the previous Foo is replaced along with the
+// labels. The replicas created by the Foo controller, which carry the template hash annotation, keep their labels and
+// controller reference, so the Foo controller reports their edits as drifts instead of losing track of them.
 func (w *Webhook) Default(ctx context.Context, obj runtime.Object) error {
-	return nil
+	bar := obj.(*v1alpha1.Bar)
+	if !w.namespaces.Contains(bar.Namespace) {
+		return nil
+	}
+	if bar.Spec.Foo == "" {
+		return nil
+	}
+
+	_, isReplica := bar.Annotations[v1alpha1.TemplateHashAnnotation]
+	if !isReplica {
+		err := metadata.SetLabel(bar, v1alpha1.FooLabel, bar.Spec.Foo)
+		if err != nil {
+			return err
+		}
+		err = metadata.SetLabel(bar, v1alpha1.ManagedByLabel, v1alpha1.ManagedByLabelValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Bars already controlled by their Foo or by another kind of resource are left untouched, as are the replicas
+	// controlled by any Foo
+	if owner := metav1.GetControllerOf(bar); owner != nil {
+		if !isFooReference(owner) || owner.Name == bar.Spec.Foo || isReplica {
+			return nil
+		}
+		removeOwnerReference(bar, owner.UID)
+	}
+
+	foo, err := w.loader.GetFoo(ctx, w.client, bar.Spec.Foo, bar.Namespace)
+	if err != nil {
+		// The validating webhook takes care of rejecting Bars referencing unexistent Foo resources
+		if errors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	return ctrl.SetControllerReference(foo, bar, w.client.Scheme())
 }
 
 // +kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1alpha1-bar,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=bars,verbs=create;update,versions=v1alpha1,name=vbar.kb.io,admissionReviewVersions=v1
@@ -60,21 +118,90 @@ func (w *Webhook) Default(ctx context.Context, obj runtime.Object) error {
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
 func (w *Webhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
 	bar := obj.(*v1alpha1.Bar)
+	if !w.namespaces.Contains(bar.Namespace) {
+		return nil
+	}
 
-	_, err := w.loader.GetFoo(ctx, w.client, bar.Spec.Foo, bar.Namespace)
+	err := validateWorkload(bar)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.loader.GetFoo(ctx, w.client, bar.Spec.Foo, bar.Namespace)
 	if err != nil {
 		return fmt.Errorf("resource references an unexistent Foo resource (%s/%s)", bar.Namespace, bar.Spec.Foo)
 	}
 
+	if !w.enforceQuotas {
+		return nil
+	}
+
+	quotas, err := w.loader.GetFooQuotas(ctx, w.client, bar.Namespace)
+	if err != nil || len(quotas) == 0 {
+		return err
+	}
+
+	bars, err := w.loader.GetNamespaceBars(ctx, w.client, bar.Namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range quotas {
+		if quota.ExceedsMaxBars(len(bars) + 1) {
+			return fmt.Errorf("the number of Bar resources would exceed the maximum of %d set in FooQuota %s/%s",
+				*quota.Spec.MaxBars, quota.Namespace, quota.Name)
+		}
+	}
+
 	return nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
 func (w *Webhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
-	return nil
+	bar := newObj.(*v1alpha1.Bar)
+	if !w.namespaces.Contains(bar.Namespace) {
+		return nil
+	}
+
+	return validateWorkload(bar)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
 func (w *Webhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
+
+// isFooReference returns true if the given owner reference points to a Foo resource.
+func isFooReference(owner *metav1.OwnerReference) bool {
+	return owner.APIVersion == v1alpha1.GroupVersion.String() && owner.Kind == "Foo"
+}
+
+// removeOwnerReference removes the owner reference with the given UID from the Bar resource, along with the
+// FooUIDLabel if it points to the same owner, so the Bar stops being a part of it.
+func removeOwnerReference(bar *v1alpha1.Bar, uid types.UID) {
+	references := bar.GetOwnerReferences()[:0]
+	for _, reference := range bar.GetOwnerReferences() {
+		if reference.UID != uid {
+			references = append(references, reference)
+		}
+	}
+	bar.SetOwnerReferences(references)
+
+	if bar.Labels[v1alpha1.FooUIDLabel] == string(uid) {
+		delete(bar.Labels, v1alpha1.FooUIDLabel)
+	}
+}
+
+// validateWorkload returns an error if the Bar describes a workload whose pod template can't be rendered into a
+// Deployment.
+func validateWorkload(bar *v1alpha1.Bar) error {
+	if bar.Spec.Workload == nil {
+		return nil
+	}
+
+	if len(bar.Spec.Workload.Template.Spec.Containers) == 0 {
+		return fmt.Errorf("the workload template must define at least one container")
+	}
+
+	return nil
+}
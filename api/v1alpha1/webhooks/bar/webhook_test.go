@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bar
+
+import (
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bar webhook", func() {
+	const namespace = "default"
+
+	var (
+		foo, other *v1alpha1.Foo
+		h          *harness.Harness
+		w          *Webhook
+	)
+
+	BeforeEach(func() {
+		foo = harness.NewFoo("foo", namespace).Build()
+		foo.UID = "foo-uid"
+		other = harness.NewFoo("other", namespace).Build()
+		other.UID = "other-uid"
+		h = harness.New(foo, other)
+		w = &Webhook{client: h.Client, loader: loader.NewLoader(), log: *h.Logger}
+	})
+
+	Context("when defaulting a Bar", func() {
+		It("stamps the labels and sets the Foo as its controller", func() {
+			bar := harness.NewBar("bar", namespace).Build()
+			bar.Spec.Foo = foo.Name
+
+			Expect(w.Default(h.Context, bar)).To(Succeed())
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, foo.Name))
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.ManagedByLabel, v1alpha1.ManagedByLabelValue))
+			Expect(metav1.GetControllerOf(bar).UID).To(Equal(foo.UID))
+		})
+
+		It("replaces the label and the controller when spec.foo changes", func() {
+			bar := harness.NewBar("bar", namespace).ForFoo(foo).WithOwner(foo).Build()
+			bar.Spec.Foo = other.Name
+
+			Expect(w.Default(h.Context, bar)).To(Succeed())
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, other.Name))
+			Expect(bar.Labels).NotTo(HaveKey(v1alpha1.FooUIDLabel))
+			Expect(bar.OwnerReferences).To(HaveLen(1))
+			Expect(metav1.GetControllerOf(bar).UID).To(Equal(other.UID))
+			Expect(bar.GetFooUID()).To(Equal(other.UID))
+		})
+
+		It("leaves the labels and the controller of the Foo replicas untouched when they are edited", func() {
+			bar := harness.NewBar("bar", namespace).ForFoo(foo).WithOwner(foo).Build()
+			bar.Annotations = map[string]string{v1alpha1.TemplateHashAnnotation: "hash"}
+			bar.Labels[v1alpha1.FooLabel] = "edited"
+			bar.Spec.Foo = other.Name
+
+			Expect(w.Default(h.Context, bar)).To(Succeed())
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, "edited"))
+			Expect(metav1.GetControllerOf(bar).UID).To(Equal(foo.UID))
+		})
+
+		It("leaves Bars controlled by other kinds of resources untouched", func() {
+			bar := harness.NewBar("bar", namespace).Build()
+			bar.Spec.Foo = foo.Name
+			deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deployment", UID: "deployment-uid"}}
+			bar.OwnerReferences = []metav1.OwnerReference{
+				*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment")),
+			}
+
+			Expect(w.Default(h.Context, bar)).To(Succeed())
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, foo.Name))
+			Expect(metav1.GetControllerOf(bar).UID).To(BeEquivalentTo("deployment-uid"))
+		})
+
+		It("only stamps the labels when the Foo doesn't exist", func() {
+			bar := harness.NewBar("bar", namespace).Build()
+			bar.Spec.Foo = "missing"
+
+			Expect(w.Default(h.Context, bar)).To(Succeed())
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, "missing"))
+			Expect(bar.OwnerReferences).To(BeEmpty())
+		})
+	})
+
+	Context("when running against the API server", func() {
+		It("defaults the Bars on admission", func() {
+			foo := harness.NewFoo("admitted-foo", namespace).Build()
+			Expect(k8sClient.Create(ctx, foo)).To(Succeed())
+
+			bar := harness.NewBar("admitted-bar", namespace).Build()
+			bar.Spec.Foo = foo.Name
+			Expect(k8sClient.Create(ctx, bar)).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bar), bar)).To(Succeed())
+			Expect(bar.Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, foo.Name))
+			Expect(metav1.GetControllerOf(bar).UID).To(Equal(foo.UID))
+		})
+	})
+})
@@ -0,0 +1,127 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package foo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Webhook describes the data structure for the foo webhook
+type Webhook struct {
+	client        client.Client
+	enforceQuotas bool
+	loader        loader.ObjectLoader
+	log           logr.Logger
+	namespaces    operatorconfig.NamespaceFilter
+}
+
+// Configure applies the watched namespaces and the FooQuotas feature gate set in the operator configuration. Foo
+// resources in other namespaces are admitted without changes and the FooQuota resources are only enforced when the
+// feature is enabled.
+func (w *Webhook) Configure(config *operatorconfig.OperatorConfig) {
+	w.enforceQuotas = config.IsFeatureEnabled(operatorconfig.FooQuotasFeature)
+	w.namespaces = config.GetNamespaceFilter()
+}
+
+// Register registers the webhook with the passed manager and log.
+func (w *Webhook) Register(mgr ctrl.Manager, log *logr.Logger) error {
+	w.client = mgr.GetClient()
+	w.loader = loader.NewLoader()
+	w.log = log.WithName("foo")
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.Foo{}).
+		WithValidator(w).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1alpha1-foo,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=foos,verbs=create;update,versions=v1alpha1,name=vfoo.kb.io,admissionReviewVersions=v1
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (w *Webhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	foo := obj.(*v1alpha1.Foo)
+	if !w.enforceQuotas || !w.namespaces.Contains(foo.Namespace) {
+		return nil
+	}
+
+	quotas, err := w.loader.GetFooQuotas(ctx, w.client, foo.Namespace)
+	if err != nil || len(quotas) == 0 {
+		return err
+	}
+
+	foos, err := w.loader.GetFoos(ctx, w.client, foo.Namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range quotas {
+		if quota.ExceedsMaxFoos(len(foos) + 1) {
+			return fmt.Errorf("the number of Foo resources would exceed the maximum of %d set in FooQuota %s/%s",
+				*quota.Spec.MaxFoos, quota.Namespace, quota.Name)
+		}
+	}
+
+	return validateDesiredReplicas(foo, quotas)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (w *Webhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldFoo := oldObj.(*v1alpha1.Foo)
+	newFoo := newObj.(*v1alpha1.Foo)
+	if !w.enforceQuotas || !w.namespaces.Contains(newFoo.Namespace) {
+		return nil
+	}
+
+	// Only scaling up is checked, so Foo resources over the quota can still be updated or scaled down
+	if newFoo.Spec.DesiredReplicas <= oldFoo.Spec.DesiredReplicas {
+		return nil
+	}
+
+	quotas, err := w.loader.GetFooQuotas(ctx, w.client, newFoo.Namespace)
+	if err != nil {
+		return err
+	}
+
+	return validateDesiredReplicas(newFoo, quotas)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (w *Webhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateDesiredReplicas returns an error if the desired replicas of the given Foo resource go beyond the limit set
+// in any of the FooQuota resources passed as a parameter.
+func validateDesiredReplicas(foo *v1alpha1.Foo, quotas []v1alpha1.FooQuota) error {
+	for _, quota := range quotas {
+		if quota.ExceedsMaxDesiredReplicas(foo.Spec.DesiredReplicas) {
+			return fmt.Errorf("the desired replicas exceed the maximum of %d set in FooQuota %s/%s",
+				*quota.Spec.MaxDesiredReplicas, quota.Namespace, quota.Name)
+		}
+	}
+
+	return nil
+}
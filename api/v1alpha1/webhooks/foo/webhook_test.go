@@ -0,0 +1,124 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package foo
+
+import (
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Foo webhook", func() {
+	const namespace = "default"
+
+	var (
+		h     *harness.Harness
+		quota *v1alpha1.FooQuota
+		w     *Webhook
+	)
+
+	newQuota := func(name, namespace string, maxFoos, maxDesiredReplicas int) *v1alpha1.FooQuota {
+		return &v1alpha1.FooQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1alpha1.FooQuotaSpec{
+				MaxDesiredReplicas: &maxDesiredReplicas,
+				MaxFoos:            &maxFoos,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		quota = newQuota("quota", namespace, 1, 3)
+		h = harness.New(quota, harness.NewFoo("existing", namespace).Build())
+		w = &Webhook{client: h.Client, loader: loader.NewLoader(), log: *h.Logger}
+		w.Configure(operatorconfig.DefaultConfig())
+	})
+
+	Context("when creating a Foo", func() {
+		It("rejects it when the namespace already has the maximum number of Foos", func() {
+			err := w.ValidateCreate(h.Context, harness.NewFoo("foo", namespace).Build())
+			Expect(err).To(MatchError(
+				"the number of Foo resources would exceed the maximum of 1 set in FooQuota default/quota"))
+		})
+
+		It("rejects it when its desired replicas exceed the maximum", func() {
+			h = harness.New(quota)
+			w.client = h.Client
+
+			err := w.ValidateCreate(h.Context, harness.NewFoo("foo", namespace).WithDesiredReplicas(4).Build())
+			Expect(err).To(MatchError("the desired replicas exceed the maximum of 3 set in FooQuota default/quota"))
+		})
+
+		It("admits it within the quota", func() {
+			h = harness.New(quota)
+			w.client = h.Client
+
+			Expect(w.ValidateCreate(h.Context, harness.NewFoo("foo", namespace).WithDesiredReplicas(3).Build())).To(
+				Succeed())
+		})
+
+		It("admits it in namespaces without quotas or not watched", func() {
+			Expect(w.ValidateCreate(h.Context, harness.NewFoo("foo", "other").Build())).To(Succeed())
+
+			w.Configure(&operatorconfig.OperatorConfig{Namespaces: []string{"other"}})
+			Expect(w.ValidateCreate(h.Context, harness.NewFoo("foo", namespace).Build())).To(Succeed())
+		})
+	})
+
+	It("admits the Foos over the quota when the FooQuotas feature is disabled", func() {
+		config := operatorconfig.DefaultConfig()
+		config.SetFeatureEnabled(operatorconfig.FooQuotasFeature, false)
+		w.Configure(config)
+
+		Expect(w.ValidateCreate(h.Context, harness.NewFoo("foo", namespace).WithDesiredReplicas(4).Build())).To(
+			Succeed())
+	})
+
+	Context("when updating a Foo", func() {
+		It("rejects scaling it up beyond the maximum", func() {
+			oldFoo := harness.NewFoo("existing", namespace).WithDesiredReplicas(2).Build()
+			newFoo := harness.NewFoo("existing", namespace).WithDesiredReplicas(4).Build()
+
+			Expect(w.ValidateUpdate(h.Context, oldFoo, newFoo)).To(MatchError(
+				"the desired replicas exceed the maximum of 3 set in FooQuota default/quota"))
+		})
+
+		It("admits scaling it down while over the maximum", func() {
+			oldFoo := harness.NewFoo("existing", namespace).WithDesiredReplicas(5).Build()
+			newFoo := harness.NewFoo("existing", namespace).WithDesiredReplicas(4).Build()
+
+			Expect(w.ValidateUpdate(h.Context, oldFoo, newFoo)).To(Succeed())
+		})
+	})
+
+	Context("when running against the API server", func() {
+		It("rejects the Foos over the quota on admission", func() {
+			Expect(k8sClient.Create(ctx, newQuota("admission-quota", "default", 5, 1))).To(Succeed())
+
+			foo := harness.NewFoo("admitted-foo", "default").WithDesiredReplicas(2).Build()
+			err := k8sClient.Create(ctx, foo)
+			Expect(err).To(MatchError(ContainSubstring("the desired replicas exceed the maximum of 1")))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(foo), foo)).NotTo(Succeed())
+		})
+	})
+})
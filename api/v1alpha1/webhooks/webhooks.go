@@ -2,10 +2,33 @@ package webhooks
 
 import (
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1/webhooks/bar"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1/webhooks/foo"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
 	"github.com/konflux-ci/operator-toolkit/webhook"
 )
 
-// EnabledWebhooks is a slice containing references to all the webhooks that have to be registered
-var EnabledWebhooks = []webhook.Webhook{
-	&bar.Webhook{},
+// AllWebhooks is a map containing references to all the webhooks, keyed by the name used to enable them
+var AllWebhooks = map[string]webhook.Webhook{
+	operatorconfig.BarWebhook: &bar.Webhook{},
+	operatorconfig.FooWebhook: &foo.Webhook{},
+}
+
+// EnabledWebhooks returns the webhooks enabled in the operator configuration, sorted by name.
+func EnabledWebhooks(config *operatorconfig.OperatorConfig) []webhook.Webhook {
+	var enabledWebhooks []webhook.Webhook
+	for _, name := range config.GetEnabledWebhooks() {
+		enabledWebhooks = append(enabledWebhooks, AllWebhooks[name])
+	}
+
+	return enabledWebhooks
+}
+
+// Configure passes the operator configuration to the enabled webhooks implementing operatorconfig.Configurable.
+// It has to be called before setting up the webhooks.
+func Configure(config *operatorconfig.OperatorConfig) {
+	for _, enabledWebhook := range EnabledWebhooks(config) {
+		if configurable, ok := enabledWebhook.(operatorconfig.Configurable); ok {
+			configurable.Configure(config)
+		}
+	}
 }
@@ -0,0 +1,43 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BarFooUIDIndexField is the name of the index used to list Bar resources by the UID of the Foo they belong to.
+const BarFooUIDIndexField = "fooUID"
+
+// SetupBarCache indexes the Bar resources by the UID of their owner, so it is possible to list the Bar resources of a
+// Foo resource without going through all the Bar resources in its namespace.
+func SetupBarCache(indexer client.FieldIndexer) error {
+	return indexer.IndexField(context.Background(), &v1alpha1.Bar{}, BarFooUIDIndexField, BarFooUIDIndexFunc)
+}
+
+// BarFooUIDIndexFunc returns the UID of the Foo resource owning the given Bar resource, if any.
+func BarFooUIDIndexFunc(obj client.Object) []string {
+	uid := obj.(*v1alpha1.Bar).GetFooUID()
+	if uid == "" {
+		return nil
+	}
+
+	return []string{string(uid)}
+}
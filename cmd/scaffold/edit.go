@@ -0,0 +1,368 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// sourceFile is a Go file being edited. Insertions are collected and only applied when formatting the file, so
+	// the positions found when parsing it stay valid.
+	sourceFile struct {
+		fset       *token.FileSet
+		file       *ast.File
+		insertions []insertion
+		path       string
+		src        []byte
+	}
+
+	// insertion is a text to be inserted at the given offset of a file.
+	insertion struct {
+		offset int
+		text   string
+	}
+
+	// keyedNode is a node of a sorted list, such as a const block or a map literal, along with the key it is sorted by.
+	keyedNode struct {
+		key   string
+		start token.Pos
+	}
+)
+
+// parseSourceFile parses the given Go source so it can be edited.
+func parseSourceFile(path string, src []byte) (*sourceFile, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sourceFile{fset: fset, file: file, path: path, src: src}, nil
+}
+
+// addConstSpec adds the given constant declaration to the const block whose constants all have the given suffix,
+// keeping it sorted by name.
+func (f *sourceFile) addConstSpec(suffix, name, text string) error {
+	for _, decl := range f.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST || !genDecl.Lparen.IsValid() {
+			continue
+		}
+
+		var nodes []keyedNode
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			if !strings.HasSuffix(valueSpec.Names[0].Name, suffix) {
+				nodes = nil
+				break
+			}
+			nodes = append(nodes, keyedNode{key: valueSpec.Names[0].Name, start: startOf(valueSpec.Doc, valueSpec)})
+		}
+
+		if len(nodes) > 0 {
+			return f.insertSorted(nodes, name, text, genDecl.Rparen)
+		}
+	}
+
+	return fmt.Errorf("%s: no const block with the %s suffix found", f.path, suffix)
+}
+
+// addImport adds the given import path to the import block.
+func (f *sourceFile) addImport(path string) error {
+	for _, decl := range f.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT || !genDecl.Lparen.IsValid() {
+			continue
+		}
+
+		var nodes []keyedNode
+		for _, spec := range genDecl.Specs {
+			importSpec := spec.(*ast.ImportSpec)
+			importPath, err := strconv.Unquote(importSpec.Path.Value)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, keyedNode{key: importPath, start: startOf(importSpec.Doc, importSpec)})
+		}
+
+		return f.insertSorted(nodes, path, strconv.Quote(path), genDecl.Rparen)
+	}
+
+	return fmt.Errorf("%s: no import block found", f.path)
+}
+
+// addInterfaceMethod adds the given method to the interface with the given name, keeping its methods sorted by name.
+func (f *sourceFile) addInterfaceMethod(interfaceName, name, text string) error {
+	typeSpec := f.findTypeSpec(interfaceName)
+	if typeSpec == nil {
+		return fmt.Errorf("%s: interface %s not found", f.path, interfaceName)
+	}
+
+	interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
+	if !ok {
+		return fmt.Errorf("%s: %s is not an interface", f.path, interfaceName)
+	}
+
+	var nodes []keyedNode
+	for _, method := range interfaceType.Methods.List {
+		if len(method.Names) > 0 {
+			nodes = append(nodes, keyedNode{key: method.Names[0].Name, start: startOf(method.Doc, method)})
+		}
+	}
+
+	return f.insertSorted(nodes, name, text, interfaceType.Methods.Closing)
+}
+
+// addMapEntry adds the given entry to the map literal assigned to the variable with the given name, keeping its
+// entries sorted by key.
+func (f *sourceFile) addMapEntry(variableName, key, text string) error {
+	literal, err := f.findCompositeLiteral(variableName)
+	if err != nil {
+		return err
+	}
+
+	var nodes []keyedNode
+	for _, element := range literal.Elts {
+		keyValue, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			return fmt.Errorf("%s: %s is not a map literal", f.path, variableName)
+		}
+		nodes = append(nodes, keyedNode{key: f.sourceOf(keyValue.Key), start: keyValue.Pos()})
+	}
+
+	return f.insertSorted(nodes, key, text, literal.Rbrace)
+}
+
+// addMethod adds the given method to the methods of the given receiver type whose name has the given prefix, keeping
+// them sorted by name. The method is added after the last of them if none sorts after it.
+func (f *sourceFile) addMethod(receiver, prefix, name, text string) error {
+	var last int
+	for i, decl := range f.file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || receiverOf(funcDecl) != receiver || !strings.HasPrefix(funcDecl.Name.Name, prefix) {
+			continue
+		}
+
+		if funcDecl.Name.Name == name {
+			return fmt.Errorf("%s: method %s already exists", f.path, name)
+		}
+		if funcDecl.Name.Name > name {
+			f.insert(startOf(funcDecl.Doc, funcDecl), text+"\n\n")
+			return nil
+		}
+		last = i + 1
+	}
+
+	if last == 0 {
+		return fmt.Errorf("%s: no method of %s with the %s prefix found", f.path, receiver, prefix)
+	}
+
+	if last < len(f.file.Decls) {
+		next := f.file.Decls[last]
+		var doc *ast.CommentGroup
+		if funcDecl, ok := next.(*ast.FuncDecl); ok {
+			doc = funcDecl.Doc
+		} else if genDecl, ok := next.(*ast.GenDecl); ok {
+			doc = genDecl.Doc
+		}
+		f.insert(startOf(doc, next), text+"\n\n")
+		return nil
+	}
+
+	f.insertions = append(f.insertions, insertion{offset: len(f.src), text: "\n" + text + "\n"})
+	return nil
+}
+
+// addSliceElement adds the given element to the slice literal assigned to the variable with the given name, keeping
+// its elements sorted.
+func (f *sourceFile) addSliceElement(variableName, element string) error {
+	literal, err := f.findCompositeLiteral(variableName)
+	if err != nil {
+		return err
+	}
+
+	for i, existing := range literal.Elts {
+		existingElement := f.sourceOf(existing)
+		if existingElement == element {
+			return fmt.Errorf("%s: %s already contains %s", f.path, variableName, element)
+		}
+		if existingElement > element {
+			f.insert(existing.Pos(), element+", ")
+			return nil
+		}
+		if i == len(literal.Elts)-1 {
+			f.insert(existing.End(), ", "+element)
+			return nil
+		}
+	}
+
+	f.insert(literal.Rbrace, element)
+	return nil
+}
+
+// format applies the insertions and returns the formatted source.
+func (f *sourceFile) format() ([]byte, error) {
+	sort.SliceStable(f.insertions, func(i, j int) bool {
+		return f.insertions[i].offset < f.insertions[j].offset
+	})
+
+	var buffer bytes.Buffer
+	var offset int
+	for _, insertion := range f.insertions {
+		buffer.Write(f.src[offset:insertion.offset])
+		buffer.WriteString(insertion.text)
+		offset = insertion.offset
+	}
+	buffer.Write(f.src[offset:])
+
+	formatted, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+
+	return formatted, nil
+}
+
+// findCompositeLiteral returns the composite literal assigned to the package variable with the given name.
+func (f *sourceFile) findCompositeLiteral(variableName string) (*ast.CompositeLit, error) {
+	for _, decl := range f.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.VAR {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, name := range valueSpec.Names {
+				if name.Name != variableName || i >= len(valueSpec.Values) {
+					continue
+				}
+
+				if literal, ok := valueSpec.Values[i].(*ast.CompositeLit); ok {
+					return literal, nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s: no literal assigned to %s found", f.path, variableName)
+}
+
+// findTypeSpec returns the declaration of the type with the given name or nil if not found.
+func (f *sourceFile) findTypeSpec(name string) *ast.TypeSpec {
+	for _, decl := range f.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			if typeSpec := spec.(*ast.TypeSpec); typeSpec.Name.Name == name {
+				return typeSpec
+			}
+		}
+	}
+
+	return nil
+}
+
+// insert queues the insertion of the given text at the given position.
+func (f *sourceFile) insert(pos token.Pos, text string) {
+	f.insertions = append(f.insertions, insertion{offset: f.fset.Position(pos).Offset, text: text})
+}
+
+// insertSorted queues the insertion of the given text in its own line, before the first node sorting after the given
+// key or, if none does, before the closing position. An error is returned if a node already has the key.
+func (f *sourceFile) insertSorted(nodes []keyedNode, key, text string, closing token.Pos) error {
+	for _, node := range nodes {
+		if node.key == key {
+			return fmt.Errorf("%s: %s already exists", f.path, key)
+		}
+	}
+
+	pos := closing
+	for _, node := range nodes {
+		if node.key > key {
+			pos = node.start
+			break
+		}
+	}
+
+	offset := f.fset.Position(pos).Offset
+	lineStart := bytes.LastIndexByte(f.src[:offset], '\n') + 1
+	f.insertions = append(f.insertions, insertion{offset: lineStart, text: text + "\n"})
+
+	return nil
+}
+
+// sourceOf returns the source code of the given node.
+func (f *sourceFile) sourceOf(node ast.Node) string {
+	return string(f.src[f.fset.Position(node.Pos()).Offset:f.fset.Position(node.End()).Offset])
+}
+
+// insertBeforeMarker inserts the given line before the line containing the given marker, as used by kubebuilder in
+// the kustomization files.
+func insertBeforeMarker(path string, src []byte, marker, line string) ([]byte, error) {
+	index := bytes.Index(src, []byte(marker))
+	if index < 0 {
+		return nil, fmt.Errorf("%s: marker %s not found", path, marker)
+	}
+	if bytes.Contains(src, []byte(line+"\n")) {
+		return nil, fmt.Errorf("%s: %s already exists", path, line)
+	}
+
+	lineStart := bytes.LastIndexByte(src[:index], '\n') + 1
+	return append(append(append([]byte{}, src[:lineStart]...), line+"\n"...), src[lineStart:]...), nil
+}
+
+// receiverOf returns the name of the receiver type of the given function or an empty string if it is not a method.
+func receiverOf(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return ""
+	}
+
+	receiverType := funcDecl.Recv.List[0].Type
+	if star, ok := receiverType.(*ast.StarExpr); ok {
+		receiverType = star.X
+	}
+	if index, ok := receiverType.(*ast.IndexExpr); ok {
+		receiverType = index.X
+	}
+	if ident, ok := receiverType.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return ""
+}
+
+// startOf returns the position of the given node, including its doc comment if any.
+func startOf(doc *ast.CommentGroup, node ast.Node) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+
+	return node.Pos()
+}
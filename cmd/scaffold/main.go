@@ -0,0 +1,57 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command scaffold adds a new kind to the operator. It generates the API types and condition helpers, a loader method
+// along with its mock, a controller with its adapter, a validating webhook and their test suites, and registers the
+// controller and the webhook so they can be enabled by name. Run it from the root of the repository:
+//
+//	go run ./cmd/scaffold -kind Baz
+//
+// The deepcopy functions, CRD, RBAC rules and webhook configuration of the new kind are then generated by running
+// make generate manifests.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var kindName, plural, root string
+	flag.StringVar(&kindName, "kind", "", "The name of the kind to add, e.g. FooQuota.")
+	flag.StringVar(&plural, "plural", "", "The plural name of the resource. Defaults to the lowercase kind in plural.")
+	flag.StringVar(&root, "root", ".", "The root directory of the repository.")
+	flag.Parse()
+
+	if kindName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	kind, err := NewKind(kindName, plural)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := Scaffold(root, kind); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s added, run make generate manifests to generate its deepcopy functions and manifests\n", kind.Name)
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Kind holds the names a new kind is referred to by in the generated code.
+type Kind struct {
+	// Name is the name of the kind, e.g. FooQuota
+	Name string
+
+	// Package is the name of the controller and webhook packages, e.g. fooquota
+	Package string
+
+	// Plural is the plural name of the resource, e.g. fooquotas
+	Plural string
+
+	// Receiver is the name of the receiver of the methods of the API type, e.g. f
+	Receiver string
+
+	// Var is the name of the variables holding a resource of the kind, e.g. fooQuota
+	Var string
+}
+
+// generatedFile is a file generated from a template for every new kind.
+type generatedFile struct {
+	// path returns the path of the file relative to the root of the repository
+	path func(kind Kind) string
+
+	// template is the name of the template the file is generated from
+	template string
+}
+
+var (
+	//go:embed templates/*.tmpl
+	templateFiles embed.FS
+
+	// generatedFiles contains the files generated for every new kind
+	generatedFiles = []generatedFile{
+		{path: inPackage("api/v1alpha1", "%s_types.go"), template: "types.go.tmpl"},
+		{path: inPackage("api/v1alpha1", "%s_conditions.go"), template: "conditions.go.tmpl"},
+		{path: inPackage("api/v1alpha1/webhooks/%s", "webhook.go"), template: "webhook.go.tmpl"},
+		{path: inPackage("api/v1alpha1/webhooks/%s", "suite_test.go"), template: "webhook_suite_test.go.tmpl"},
+		{path: inPackage("config/samples", "appstudio_v1alpha1_%s.yaml"), template: "sample.yaml.tmpl"},
+		{path: inPackage("controllers/%s", "adapter.go"), template: "adapter.go.tmpl"},
+		{path: inPackage("controllers/%s", "adapter_test.go"), template: "adapter_test.go.tmpl"},
+		{path: inPackage("controllers/%s", "controller.go"), template: "controller.go.tmpl"},
+		{path: inPackage("controllers/%s", "suite_test.go"), template: "controller_suite_test.go.tmpl"},
+	}
+
+	// kindNameRegexp is used to validate the name of new kinds
+	kindNameRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+	// reservedVars contains the identifiers used in the templates, which can't be used as the variable name of a kind
+	reservedVars = []string{"a", "adapter", "adapters", "builder", "c", "client", "cluster", "conditions", "config",
+		"context", "controller", "ctrl", "ctx", "err", "errors", "h", "harness", "key", "l", "loader", "log", "logger",
+		"logr", "m", "meta", "metav1", "mgr", "obj", "predicate", "req", "result", "runtime", "span", "time", "toolkit",
+		"tracing", "v1alpha1", "w"}
+)
+
+// NewKind returns the names used for the kind with the given name. The plural name defaults to the lowercase name
+// followed by "s", or "es" or "ies" when needed.
+func NewKind(name, plural string) (Kind, error) {
+	if !kindNameRegexp.MatchString(name) {
+		return Kind{}, fmt.Errorf("invalid kind %q: it has to be alphanumeric and start with an uppercase letter", name)
+	}
+
+	kind := Kind{
+		Name:     name,
+		Package:  strings.ToLower(name),
+		Plural:   plural,
+		Receiver: strings.ToLower(name[:1]),
+		Var:      lowerCamelCase(name),
+	}
+	if kind.Plural == "" {
+		kind.Plural = pluralize(kind.Package)
+	}
+
+	if token.IsKeyword(kind.Package) {
+		return Kind{}, fmt.Errorf("invalid kind %q: %s is a Go keyword", name, kind.Package)
+	}
+	for _, reserved := range reservedVars {
+		if kind.Var == reserved {
+			return Kind{}, fmt.Errorf("invalid kind %q: %s is used by the generated code", name, kind.Var)
+		}
+	}
+
+	return kind, nil
+}
+
+// Scaffold adds the given kind to the repository in the given root directory, generating its API types, controller,
+// webhook and loader method and registering them. Nothing is written if any of the files can't be generated or
+// changed.
+func Scaffold(root string, kind Kind) error {
+	templates, err := template.ParseFS(templateFiles, "templates/*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{}
+	for _, generated := range generatedFiles {
+		path := generated.path(kind)
+		if _, err := os.Stat(filepath.Join(root, path)); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+
+		var buffer bytes.Buffer
+		err := templates.ExecuteTemplate(&buffer, generated.template, kind)
+		if err != nil {
+			return err
+		}
+
+		files[path] = buffer.Bytes()
+		if filepath.Ext(path) == ".go" {
+			files[path], err = format.Source(files[path])
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	module, err := readModulePath(root)
+	if err != nil {
+		return err
+	}
+
+	snippets, err := renderSnippets(templates, kind, "loaderInterfaceMethod", "loaderMethod", "mockMethodName",
+		"mockOnMethod", "mockMethod", "snapshotMethod")
+	if err != nil {
+		return err
+	}
+
+	edits := map[string]func(f *sourceFile) error{
+		"api/v1alpha1/webhooks/webhooks.go": func(f *sourceFile) error {
+			return firstError(
+				f.addImport(module+"/api/v1alpha1/webhooks/"+kind.Package),
+				f.addMapEntry("AllWebhooks", "operatorconfig."+kind.Name+"Webhook",
+					fmt.Sprintf("operatorconfig.%sWebhook: &%s.Webhook{},", kind.Name, kind.Package)),
+			)
+		},
+		"controllers/controllers.go": func(f *sourceFile) error {
+			return firstError(
+				f.addImport(module+"/controllers/"+kind.Package),
+				f.addMapEntry("AllControllers", "operatorconfig."+kind.Name+"Controller",
+					fmt.Sprintf("operatorconfig.%sController: &%s.Controller{},", kind.Name, kind.Package)),
+			)
+		},
+		"loader/loader.go": func(f *sourceFile) error {
+			return firstError(
+				f.addInterfaceMethod("ObjectLoader", "Get"+kind.Name, snippets["loaderInterfaceMethod"]),
+				f.addMethod("loader", "Get", "Get"+kind.Name, snippets["loaderMethod"]),
+			)
+		},
+		"loader/loader_mock.go": func(f *sourceFile) error {
+			return firstError(
+				f.addConstSpec("Method", "Get"+kind.Name+"Method", snippets["mockMethodName"]),
+				f.addMethod("MockLoader", "OnGet", "OnGet"+kind.Name, snippets["mockOnMethod"]),
+				f.addMethod("MockLoader", "Get", "Get"+kind.Name, snippets["mockMethod"]),
+			)
+		},
+		"loader/loader_snapshot.go": func(f *sourceFile) error {
+			return f.addMethod("snapshotLoader", "Get", "Get"+kind.Name, snippets["snapshotMethod"])
+		},
+		"operatorconfig/operatorconfig.go": func(f *sourceFile) error {
+			return firstError(
+				f.addConstSpec("Controller", kind.Name+"Controller",
+					fmt.Sprintf("%sController = %q", kind.Name, kind.Package)),
+				f.addConstSpec("Webhook", kind.Name+"Webhook", fmt.Sprintf("%sWebhook = %q", kind.Name, kind.Package)),
+				f.addSliceElement("ControllerNames", kind.Name+"Controller"),
+				f.addSliceElement("WebhookNames", kind.Name+"Webhook"),
+			)
+		},
+	}
+
+	for path, edit := range edits {
+		src, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return err
+		}
+
+		f, err := parseSourceFile(path, src)
+		if err != nil {
+			return err
+		}
+
+		if err := edit(f); err != nil {
+			return err
+		}
+
+		files[path], err = f.format()
+		if err != nil {
+			return err
+		}
+	}
+
+	markers := []struct {
+		path   string
+		marker string
+		line   string
+	}{
+		{
+			path:   "config/crd/kustomization.yaml",
+			marker: "#+kubebuilder:scaffold:crdkustomizeresource",
+			line:   fmt.Sprintf("- bases/appstudio.redhat.com_%s.yaml", kind.Plural),
+		},
+		{
+			path:   "config/samples/kustomization.yaml",
+			marker: "#+kubebuilder:scaffold:manifestskustomizesamples",
+			line:   fmt.Sprintf("- appstudio_v1alpha1_%s.yaml", kind.Package),
+		},
+	}
+
+	for _, marker := range markers {
+		src, err := os.ReadFile(filepath.Join(root, marker.path))
+		if err != nil {
+			return err
+		}
+
+		files[marker.path], err = insertBeforeMarker(marker.path, src, marker.marker, marker.line)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The directories are created first, so a file can't be written before a directory fails to be created
+	for path := range files {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755); err != nil {
+			return err
+		}
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(root, path), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// firstError returns the first of the given errors which is not nil.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderSnippets executes the given templates with the given kind, returning their output by template name.
+func renderSnippets(templates *template.Template, kind Kind, names ...string) (map[string]string, error) {
+	snippets := map[string]string{}
+	for _, name := range names {
+		var buffer strings.Builder
+		if err := templates.ExecuteTemplate(&buffer, name, kind); err != nil {
+			return nil, err
+		}
+		snippets[name] = buffer.String()
+	}
+
+	return snippets, nil
+}
+
+// inPackage returns a function building the path of a file from the given directory and file name, in which %s is
+// replaced with the package name of the kind.
+func inPackage(dir, name string) func(kind Kind) string {
+	return func(kind Kind) string {
+		return filepath.Join(strings.ReplaceAll(dir, "%s", kind.Package), strings.ReplaceAll(name, "%s", kind.Package))
+	}
+}
+
+// lowerCamelCase returns the given name with its leading uppercase letters in lowercase, keeping the last one of an
+// acronym followed by a word in uppercase, e.g. HTTPRoute becomes httpRoute.
+func lowerCamelCase(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// pluralize returns the plural of the given lowercase name.
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsAny(name[len(name)-2:len(name)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	default:
+		return name + "s"
+	}
+}
+
+// readModulePath returns the module path declared in the go.mod file of the given root directory.
+func readModulePath(root string) (string, error) {
+	file, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`), nil
+		}
+	}
+
+	return "", fmt.Errorf("no module declared in %s", file.Name())
+}
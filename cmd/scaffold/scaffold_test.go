@@ -0,0 +1,286 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewKind", func() {
+	It("derives the names of the kind", func() {
+		kind, err := NewKind("FooQuota", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind).To(Equal(Kind{
+			Name:     "FooQuota",
+			Package:  "fooquota",
+			Plural:   "fooquotas",
+			Receiver: "f",
+			Var:      "fooQuota",
+		}))
+	})
+
+	It("pluralizes the kind unless the plural is set", func() {
+		for name, plural := range map[string]string{"Baz": "bazes", "Policy": "policies", "Gateway": "gateways"} {
+			kind, err := NewKind(name, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kind.Plural).To(Equal(plural))
+		}
+
+		kind, err := NewKind("Baz", "bazzes")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind.Plural).To(Equal("bazzes"))
+	})
+
+	It("keeps the last letter of a leading acronym in uppercase", func() {
+		kind, err := NewKind("HTTPRoute", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind.Var).To(Equal("httpRoute"))
+	})
+
+	It("rejects invalid names", func() {
+		for _, name := range []string{"", "baz", "Baz-Qux", "Type", "Client"} {
+			_, err := NewKind(name, "")
+			Expect(err).To(HaveOccurred(), name)
+		}
+	})
+})
+
+var _ = Describe("Scaffold", func() {
+	// editedFiles are copied from the repository, so the kind is registered in the real files
+	editedFiles := []string{
+		"api/v1alpha1/webhooks/webhooks.go",
+		"config/crd/kustomization.yaml",
+		"config/samples/kustomization.yaml",
+		"controllers/controllers.go",
+		"go.mod",
+		"loader/loader.go",
+		"loader/loader_mock.go",
+		"loader/loader_snapshot.go",
+		"operatorconfig/operatorconfig.go",
+	}
+
+	var (
+		kind Kind
+		root string
+	)
+
+	readFile := func(path string) string {
+		content, err := os.ReadFile(filepath.Join(root, path))
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		for _, path := range editedFiles {
+			content, err := os.ReadFile(filepath.Join("..", "..", path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, path), content, 0644)).To(Succeed())
+		}
+
+		var err error
+		kind, err = NewKind("Baz", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("generates Go files that build with the rest of the module", func() {
+		// The module is copied as a whole, as the generated files depend on most of its packages
+		module := GinkgoT().TempDir()
+		Expect(copyModule(filepath.Join("..", ".."), module)).To(Succeed())
+		Expect(Scaffold(module, kind)).To(Succeed())
+
+		for _, generated := range generatedFiles {
+			Expect(filepath.Join(module, generated.path(kind))).To(BeAnExistingFile())
+		}
+
+		// The DeepCopy methods are generated by "make generate", which isn't available here
+		deepCopy := &bytes.Buffer{}
+		Expect(deepCopyTemplate.Execute(deepCopy, kind)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(module, "api", "v1alpha1", "zz_generated.deepcopy_"+kind.Package+".go"),
+			deepCopy.Bytes(), 0644)).To(Succeed())
+
+		cmd := exec.Command("go", "vet", "./...")
+		cmd.Dir = module
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=readonly", "GOWORK=off")
+		output, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(output))
+	})
+
+	It("registers the controller and the webhook in order", func() {
+		Expect(Scaffold(root, kind)).To(Succeed())
+
+		Expect(readFile("operatorconfig/operatorconfig.go")).To(And(
+			MatchRegexp(`BarController\s+= "bar"\n\s+BazController\s+= "baz"\n\s+FooController`),
+			MatchRegexp(`BarWebhook = "bar"\n\s+BazWebhook = "baz"\n\s+FooWebhook`),
+			ContainSubstring("ControllerNames = []string{BarController, BazController, FooController"),
+			ContainSubstring("WebhookNames = []string{BarWebhook, BazWebhook, FooWebhook"),
+		))
+		Expect(readFile("controllers/controllers.go")).To(And(
+			ContainSubstring(`"github.com/konflux-ci/operator-toolkit-example/controllers/baz"`),
+			MatchRegexp(`operatorconfig.BazController:\s+&baz.Controller{},\n\s+operatorconfig.FooController`),
+		))
+		Expect(readFile("api/v1alpha1/webhooks/webhooks.go")).To(And(
+			ContainSubstring(`"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1/webhooks/baz"`),
+			ContainSubstring("operatorconfig.BazWebhook: &baz.Webhook{},"),
+		))
+		Expect(readFile("config/crd/kustomization.yaml")).To(
+			ContainSubstring("- bases/appstudio.redhat.com_bazes.yaml\n#+kubebuilder:scaffold:crdkustomizeresource"))
+	})
+
+	It("adds the loader method to the loaders", func() {
+		Expect(Scaffold(root, kind)).To(Succeed())
+
+		Expect(readFile("loader/loader.go")).To(And(
+			MatchRegexp(`GetBaz\(ctx context.Context, cli client.Client, name, namespace string\) \(\*v1alpha1.Baz, error\)\n\s+GetFoo\(`),
+			ContainSubstring("func (l *loader) GetBaz("),
+		))
+		Expect(readFile("loader/loader_mock.go")).To(And(
+			ContainSubstring(`GetBazMethod           = "GetBaz"`),
+			ContainSubstring("func (m *MockLoader) OnGetBaz() *MockExpectation[*v1alpha1.Baz]"),
+			ContainSubstring("func (m *MockLoader) GetBaz("),
+		))
+		Expect(readFile("loader/loader_snapshot.go")).To(ContainSubstring("func (l *snapshotLoader) GetBaz("))
+	})
+
+	It("doesn't change anything when the kind already exists", func() {
+		Expect(Scaffold(root, kind)).To(Succeed())
+		controllers := readFile("controllers/controllers.go")
+		Expect(os.Remove(filepath.Join(root, generatedFiles[0].path(kind)))).To(Succeed())
+
+		Expect(Scaffold(root, kind)).To(MatchError(ContainSubstring("already exists")))
+		Expect(readFile("controllers/controllers.go")).To(Equal(controllers))
+		Expect(filepath.Join(root, generatedFiles[0].path(kind))).NotTo(BeAnExistingFile())
+	})
+
+	It("doesn't write any file when one of the files can't be changed", func() {
+		controllers := readFile("controllers/controllers.go")
+		Expect(os.WriteFile(filepath.Join(root, "loader", "loader_snapshot.go"), []byte("package loader\n"),
+			0644)).To(Succeed())
+
+		Expect(Scaffold(root, kind)).To(HaveOccurred())
+		Expect(readFile("controllers/controllers.go")).To(Equal(controllers))
+		for _, generated := range generatedFiles {
+			Expect(filepath.Join(root, generated.path(kind))).NotTo(BeAnExistingFile())
+		}
+	})
+
+	It("returns an error when a snippet can't be rendered", func() {
+		templates := template.Must(template.New("loaderMethod").Parse("func Get{{.Name}}() {}"))
+
+		snippets, err := renderSnippets(templates, kind, "loaderMethod")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snippets).To(HaveKeyWithValue("loaderMethod", "func GetBaz() {}"))
+
+		_, err = renderSnippets(templates, kind, "loaderMethod", "mockMethod")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// deepCopyTemplate stands in for the DeepCopy methods controller-gen generates for a kind
+var deepCopyTemplate = template.Must(template.New("deepcopy").Parse(`package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func (in *{{.Name}}) DeepCopyInto(out *{{.Name}}) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Status.Conditions != nil {
+		out.Status.Conditions = make([]metav1.Condition, len(in.Status.Conditions))
+		for i := range in.Status.Conditions {
+			in.Status.Conditions[i].DeepCopyInto(&out.Status.Conditions[i])
+		}
+	}
+}
+
+func (in *{{.Name}}) DeepCopy() *{{.Name}} {
+	if in == nil {
+		return nil
+	}
+	out := new({{.Name}})
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *{{.Name}}) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *{{.Name}}List) DeepCopyInto(out *{{.Name}}List) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]{{.Name}}, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *{{.Name}}List) DeepCopy() *{{.Name}}List {
+	if in == nil {
+		return nil
+	}
+	out := new({{.Name}}List)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *{{.Name}}List) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+`))
+
+// copyModule copies the Go module in src to dst, leaving out the files that aren't needed to build it
+func copyModule(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if relative == ".git" || relative == "bin" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, relative), 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, relative), content, 0644)
+	})
+}
@@ -2,53 +2,497 @@ package bar
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/adapters"
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
 	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
 	"github.com/konflux-ci/operator-toolkit/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ConfigHashAnnotation is the annotation of the Deployment pods containing the hash of the workload config, so
+	// they are rolled out when it changes
+	ConfigHashAnnotation = "appstudio.redhat.com/config-hash"
+
+	// WorkloadHashAnnotation is the annotation of the workload resources containing the hash of the spec they were
+	// rendered from
+	WorkloadHashAnnotation = "appstudio.redhat.com/workload-hash"
+)
+
+// Reasons of the events recorded for orphaned Bar resources.
+const (
+	// AdoptedEventReason is the reason of the event recorded when the Foo resource of an orphaned Bar is found
+	AdoptedEventReason = "Adopted"
+
+	// OrphanDeletedEventReason is the reason of the event recorded when an orphaned Bar is deleted
+	OrphanDeletedEventReason = "OrphanDeleted"
+
+	// OrphanedEventReason is the reason of the event recorded when the Foo resource of a Bar is not found
+	OrphanedEventReason = "Orphaned"
 )
 
 // Adapter holds the objects needed to reconcile a Bar resource.
 type adapter struct {
-	bar    *v1alpha1.Bar // this is the kind of resource this adapter reconciles
-	client client.Client
-	ctx    context.Context
-	loader loader.ObjectLoader
-	logger *logr.Logger
+	adapters.Adapter[*v1alpha1.Bar, loader.ObjectLoader]
+	bar                *v1alpha1.Bar // this is the kind of resource this adapter reconciles
+	drainFinalizerName string
+	orphanGracePeriod  time.Duration
+	orphanPolicy       operatorconfig.OrphanPolicy
+	recorder           record.EventRecorder
 }
 
-// NewAdapter creates and returns an Adapter instance.
-func NewAdapter(ctx context.Context, client client.Client, bar *v1alpha1.Bar, loader loader.ObjectLoader, logger *logr.Logger) *adapter {
+// NewAdapter creates and returns an Adapter instance. Orphaned Bars are handled using the default orphan policy and
+// the default drain finalizer is used, unless the drainFinalizerName field is changed.
+func NewAdapter(ctx context.Context, client client.Client, bar *v1alpha1.Bar, loader loader.ObjectLoader,
+	recorder record.EventRecorder, logger *logr.Logger) *adapter {
+	defaults := operatorconfig.DefaultConfig()
+
 	return &adapter{
-		bar:    bar,
-		client: client,
-		ctx:    ctx,
-		loader: loader,
-		logger: logger,
+		Adapter:            adapters.New(ctx, client, bar, loader, logger),
+		bar:                bar,
+		drainFinalizerName: defaultDrainFinalizerName,
+		orphanGracePeriod:  defaults.Orphans.GracePeriod.Duration,
+		orphanPolicy:       defaults.Orphans.Policy,
+		recorder:           recorder,
+	}
+}
+
+// defaultDrainFinalizerName is the drain finalizer added to the Bar resources when no finalizer domain is configured.
+// Bar resources still containing it once a different domain is configured get it replaced by the configured one.
+const defaultDrainFinalizerName string = "appstudio.redhat.com/drain"
+
+// EnsureDrainIsFinished is an operation that will ensure that Bars being deleted are only released once they
+// acknowledge the drain request or their drain deadline is reached. Bars deleted without being asked to drain first,
+// e.g. by users or because they are orphaned, are given the termination grace period of their Foo, or the default one
+// if the Foo doesn't exist. No other operations are executed for Bars being deleted. This controller is the only one
+// removing the drain finalizer from the Bars in the cluster it runs in.
+func (a *adapter) EnsureDrainIsFinished() (controller.OperationResult, error) {
+	finalizerList := []adapters.Finalizer{{
+		Name:     a.drainFinalizerName,
+		Finalize: a.finalizeDrain,
+	}}
+
+	// Bars added before the finalizer domain was configured are still drained with the default drain finalizer
+	if a.drainFinalizerName != defaultDrainFinalizerName {
+		finalizerList = append(finalizerList, adapters.Finalizer{
+			Name:     defaultDrainFinalizerName,
+			Finalize: a.finalizeDrain,
+		})
+	}
+
+	return a.RunFinalizers(finalizerList...)
+}
+
+// EnsureFinalizerIsAdded is an operation that will ensure that the Bar contains the drain finalizer, so it can't be
+// deleted before it is drained. Bars created by the Foo controller contain it from the beginning. The default drain
+// finalizer is replaced by the configured one when a different finalizer domain is used, as nothing would remove it
+// otherwise. Bars whose Foo gives them no time to drain don't contain it, so they are deleted right away.
+func (a *adapter) EnsureFinalizerIsAdded() (controller.OperationResult, error) {
+	gracePeriod, err := a.getDrainGracePeriod()
+	if err != nil {
+		return controller.RequeueWithError(err)
+	}
+
+	if gracePeriod == 0 {
+		if !controllerutil.ContainsFinalizer(a.bar, a.drainFinalizerName) &&
+			!controllerutil.ContainsFinalizer(a.bar, defaultDrainFinalizerName) {
+			return controller.ContinueProcessing()
+		}
+
+		a.Logger.Info("Removing the drain finalizer, as the Foo gives no time to drain")
+		err := a.Patch(func() {
+			controllerutil.RemoveFinalizer(a.bar, a.drainFinalizerName)
+			controllerutil.RemoveFinalizer(a.bar, defaultDrainFinalizerName)
+		})
+
+		return controller.RequeueOnErrorOrContinue(err)
+	}
+
+	if a.drainFinalizerName != defaultDrainFinalizerName &&
+		controllerutil.ContainsFinalizer(a.bar, defaultDrainFinalizerName) {
+		a.Logger.Info("Replacing the default drain finalizer", "Finalizer", a.drainFinalizerName)
+		err := a.Patch(func() {
+			controllerutil.RemoveFinalizer(a.bar, defaultDrainFinalizerName)
+			controllerutil.AddFinalizer(a.bar, a.drainFinalizerName)
+		})
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+	}
+
+	return a.AddFinalizers(a.drainFinalizerName)
+}
+
+// EnsureOrphanIsHandled is an operation that will ensure that Bars whose Foo doesn't exist are marked as orphaned
+// and handled according to the orphan policy. Orphaned Bars are not requeued unless they have to be deleted after
+// the grace period, as they are reconciled again when their Foo is created. Bars whose Foo is found again are marked
+// as adopted.
+func (a *adapter) EnsureOrphanIsHandled() (controller.OperationResult, error) {
+	_, err := a.Loader.GetFoo(a.Context, a.Client, a.bar.Spec.Foo, a.bar.Namespace)
+	if err != nil && !errors.IsNotFound(err) {
+		return controller.RequeueWithError(err)
+	}
+
+	if err == nil {
+		if !a.bar.IsOrphaned() {
+			return controller.ContinueProcessing()
+		}
+
+		a.recorder.Eventf(a.bar, corev1.EventTypeNormal, AdoptedEventReason, "Foo %s found", a.bar.Spec.Foo)
+
+		return controller.RequeueOnErrorOrContinue(a.PatchStatus(a.bar.MarkAdopted))
 	}
+
+	if !a.bar.IsOrphaned() {
+		a.Logger.Info("Foo not found, marking the Bar as orphaned", "Foo", a.bar.Spec.Foo)
+		a.recorder.Eventf(a.bar, corev1.EventTypeWarning, OrphanedEventReason, "Foo %s not found", a.bar.Spec.Foo)
+		err = a.PatchStatus(a.bar.MarkOrphaned)
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+	}
+
+	if a.orphanPolicy != operatorconfig.OrphanPolicyDelete {
+		return controller.StopProcessing()
+	}
+
+	orphanedFor := time.Since(a.bar.GetOrphanedTime().Time)
+	if orphanedFor < a.orphanGracePeriod {
+		return controller.RequeueAfter(a.orphanGracePeriod-orphanedFor, nil)
+	}
+
+	a.Logger.Info("Deleting orphaned Bar", "OrphanedFor", orphanedFor.Round(time.Second).String())
+	a.recorder.Eventf(a.bar, corev1.EventTypeNormal, OrphanDeletedEventReason,
+		"Deleted after being orphaned for %s", orphanedFor.Round(time.Second))
+	err = a.Client.Delete(a.Context, a.bar)
+	if err != nil && !errors.IsNotFound(err) {
+		return controller.RequeueWithError(err)
+	}
+
+	return controller.StopProcessing()
+}
+
+// EnsureLabelsAreSet is an operation that will ensure that the Bar carries the labels its Foo selects its replicas
+// with. The labels are usually stamped at admission time by the Bar webhook, so this operation only patches Bars
+// created while the webhooks were disabled. Labels already set are left untouched.
+func (a *adapter) EnsureLabelsAreSet() (controller.OperationResult, error) {
+	_, hasFoo := a.bar.Labels[v1alpha1.FooLabel]
+	_, hasManagedBy := a.bar.Labels[v1alpha1.ManagedByLabel]
+	if a.bar.Spec.Foo == "" || (hasFoo && hasManagedBy) {
+		return controller.ContinueProcessing()
+	}
+
+	return controller.RequeueOnErrorOrContinue(a.Patch(func() {
+		if a.bar.Labels == nil {
+			a.bar.Labels = map[string]string{}
+		}
+		if !hasFoo {
+			a.bar.Labels[v1alpha1.FooLabel] = a.bar.Spec.Foo
+		}
+		if !hasManagedBy {
+			a.bar.Labels[v1alpha1.ManagedByLabel] = v1alpha1.ManagedByLabelValue
+		}
+	}))
 }
 
-// EnsureOwnerReferenceIsSet is an operation that will ensure that the owner reference is set.
+// EnsureOwnerReferenceIsSet is an operation that will ensure that the owner reference is set. The owner reference is
+// usually set at admission time by the Bar webhook, so this operation only patches Bars created while the webhooks
+// were disabled. Bars already controlled by another Foo keep their controller, as that Foo reports or corrects the
+// change of their spec.foo as a drift.
 func (a *adapter) EnsureOwnerReferenceIsSet() (controller.OperationResult, error) {
-	foo, err := a.loader.GetFoo(a.ctx, a.client, a.bar.Spec.Foo, a.bar.Namespace)
+	if owner := metav1.GetControllerOf(a.bar); owner != nil && owner.Kind == "Foo" && owner.Name != a.bar.Spec.Foo {
+		return controller.ContinueProcessing()
+	}
+
+	foo, err := a.Loader.GetFoo(a.Context, a.Client, a.bar.Spec.Foo, a.bar.Namespace)
 	if err != nil {
 		return controller.RequeueWithError(err)
 	}
 
-	patch := client.MergeFrom(a.bar.DeepCopy())
-	err = ctrl.SetControllerReference(foo, a.bar, a.client.Scheme())
+	return controller.RequeueOnErrorOrContinue(a.SetControllerReference(foo))
+}
+
+// EnsureReconcileRequestIsHandled is an operation that will ensure that the value of the requested-at annotation of
+// the Bar is reported in its status once its workload is up to date, so whoever requested the reconcile can tell it
+// has been handled.
+func (a *adapter) EnsureReconcileRequestIsHandled() (controller.OperationResult, error) {
+	return a.MarkReconcileRequestHandled(v1alpha1.ReconcileRequestedAtAnnotation)
+}
+
+// EnsureWorkloadIsReconciled is an operation that will ensure that the ConfigMap and the Deployment described in the
+// Bar workload exist and match it, reporting the readiness of the Deployment in the Bar status. The workload resources
+// are deleted when the Bar has no workload.
+func (a *adapter) EnsureWorkloadIsReconciled() (controller.OperationResult, error) {
+	if a.bar.Spec.Workload == nil {
+		err := a.deleteWorkload()
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+
+		return controller.RequeueOnErrorOrContinue(a.PatchStatus(a.bar.RemoveWorkloadStatus))
+	}
+
+	configMap := a.newConfigMap()
+	applied, err := a.applyWorkloadResource(configMap, &corev1.ConfigMap{})
 	if err != nil {
 		return controller.RequeueWithError(err)
 	}
+	if !applied {
+		return a.stopOnWorkloadConflict("ConfigMap")
+	}
 
-	err = a.client.Patch(a.ctx, a.bar, patch)
-	if err != nil && !errors.IsNotFound(err) {
+	deployment, err := a.newDeployment(configMap)
+	if err != nil {
 		return controller.RequeueWithError(err)
 	}
+	applied, err = a.applyWorkloadResource(deployment, &appsv1.Deployment{})
+	if err != nil {
+		return controller.RequeueWithError(err)
+	}
+	if !applied {
+		return a.stopOnWorkloadConflict("Deployment")
+	}
+
+	return controller.RequeueOnErrorOrContinue(a.PatchStatus(func() {
+		replicas := *deployment.Spec.Replicas
+		a.bar.Status.Workload = &v1alpha1.WorkloadStatus{
+			AvailableReplicas: deployment.Status.AvailableReplicas,
+			Replicas:          replicas,
+		}
+		if deployment.Status.ObservedGeneration >= deployment.Generation &&
+			deployment.Status.UpdatedReplicas == replicas && deployment.Status.AvailableReplicas == replicas {
+			a.bar.MarkWorkloadReady()
+		} else {
+			a.bar.MarkWorkloadProgressing(fmt.Sprintf("%d of %d replicas updated and available",
+				deployment.Status.AvailableReplicas, replicas))
+		}
+	}))
+}
+
+// finalizeDrain asks the Bar to drain if it wasn't asked yet and returns true once it is drained or its drain deadline
+// is reached, along with the time left otherwise. Bars whose Foo gives them no time to drain are released right away.
+func (a *adapter) finalizeDrain() (bool, time.Duration, error) {
+	if !a.bar.IsDraining() {
+		gracePeriod, err := a.getDrainGracePeriod()
+		if err != nil {
+			return false, 0, err
+		}
+
+		if gracePeriod == 0 {
+			a.Logger.Info("Releasing Bar", "Drained", false)
+			return true, 0, nil
+		}
+
+		err = a.PatchStatus(func() {
+			a.bar.MarkDraining(time.Now().Add(gracePeriod))
+		})
+		if err != nil {
+			return false, 0, err
+		}
+	}
+
+	if timeLeft := a.bar.GetDrainTimeLeft(time.Now()); timeLeft > 0 {
+		return false, timeLeft, nil
+	}
+
+	a.Logger.Info("Releasing Bar", "Drained", a.bar.IsDrained())
+
+	return true, 0, nil
+}
+
+// getDrainGracePeriod returns the termination grace period of the Foo of the Bar, or the default one if the Foo doesn't
+// exist.
+func (a *adapter) getDrainGracePeriod() (time.Duration, error) {
+	foo, err := a.Loader.GetFoo(a.Context, a.Client, a.bar.Spec.Foo, a.bar.Namespace)
+	if errors.IsNotFound(err) {
+		return time.Duration(v1alpha1.DefaultTerminationGracePeriodSeconds) * time.Second, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return foo.GetTerminationGracePeriod(), nil
+}
+
+// applyWorkloadResource creates the given workload resource or updates it if it doesn't match the given one anymore,
+// either because the Bar spec changed or because the resource was edited. The given object is updated with the
+// current state of the resource. The existing parameter is an empty object of the same kind used to load the resource.
+// False is returned when a resource with the same name exists and is not controlled by the Bar. As the cache only
+// holds the resources carrying the managed-by label, the ones without it are only found when failing to create them.
+func (a *adapter) applyWorkloadResource(obj, existing client.Object) (bool, error) {
+	err := a.Client.Get(a.Context, client.ObjectKeyFromObject(obj), existing)
+	if errors.IsNotFound(err) {
+		a.Logger.Info("Creating workload resource", "Kind", fmt.Sprintf("%T", obj), "Name", obj.GetName())
+		err = a.Client.Create(a.Context, obj)
+		if errors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !metav1.IsControlledBy(existing, a.bar) {
+		return false, nil
+	}
+
+	if isWorkloadResourceUpToDate(obj, existing) {
+		return true, a.Client.Get(a.Context, client.ObjectKeyFromObject(obj), obj)
+	}
+
+	a.Logger.Info("Updating workload resource", "Kind", fmt.Sprintf("%T", obj), "Name", obj.GetName())
+	obj.SetResourceVersion(existing.GetResourceVersion())
+
+	return true, a.Client.Update(a.Context, obj)
+}
+
+// stopOnWorkloadConflict marks the workload of the Bar as not ready, as the resource of the given kind named after the
+// Bar exists and is not controlled by it, and stops processing the Bar. Retrying wouldn't help until the resource is
+// removed, which isn't watched, so the Bar is reconciled again once it changes.
+func (a *adapter) stopOnWorkloadConflict(kind string) (controller.OperationResult, error) {
+	message := fmt.Sprintf("%s %s already exists and is not controlled by the Bar", kind, a.bar.Name)
+	a.Logger.Info("Workload conflict", "Kind", kind, "Name", a.bar.Name)
+
+	err := a.PatchStatus(func() {
+		a.bar.MarkWorkloadConflict(message)
+	})
+	if err != nil {
+		return controller.RequeueWithError(err)
+	}
+
+	return controller.StopProcessing()
+}
+
+// deleteWorkload deletes the workload resources controlled by the Bar, if any.
+func (a *adapter) deleteWorkload() error {
+	for _, obj := range []client.Object{&corev1.ConfigMap{}, &appsv1.Deployment{}} {
+		err := a.Client.Get(a.Context, client.ObjectKeyFromObject(a.bar), obj)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if metav1.IsControlledBy(obj, a.bar) {
+			a.Logger.Info("Deleting workload resource", "Kind", fmt.Sprintf("%T", obj), "Name", obj.GetName())
+			err = a.Client.Delete(a.Context, obj)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// newConfigMap returns the ConfigMap of the Bar workload.
+func (a *adapter) newConfigMap() *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: a.newWorkloadObjectMeta(hash(a.bar.Spec.Workload.Config)),
+		Data:       a.bar.Spec.Workload.Config,
+	}
+	_ = ctrl.SetControllerReference(a.bar, configMap, a.Client.Scheme())
+
+	return configMap
+}
+
+// newDeployment returns the Deployment of the Bar workload, annotating its pods with the hash of the given ConfigMap.
+func (a *adapter) newDeployment(configMap *corev1.ConfigMap) (*appsv1.Deployment, error) {
+	workload := a.bar.Spec.Workload
+	selector := map[string]string{v1alpha1.BarLabel: a.bar.Name}
+
+	template := workload.Template.DeepCopy()
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	for key, value := range selector {
+		template.Labels[key] = value
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[ConfigHashAnnotation] = configMap.Annotations[WorkloadHashAnnotation]
+
+	replicas := int32(1)
+	if workload.Replicas != nil {
+		replicas = *workload.Replicas
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: a.newWorkloadObjectMeta(hash(struct {
+			Replicas int32
+			Template *corev1.PodTemplateSpec
+		}{replicas, template})),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: *template,
+		},
+	}
+
+	return deployment, ctrl.SetControllerReference(a.bar, deployment, a.Client.Scheme())
+}
+
+// newWorkloadObjectMeta returns the metadata of a workload resource rendered from a spec with the given hash.
+func (a *adapter) newWorkloadObjectMeta(specHash string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      a.bar.Name,
+		Namespace: a.bar.Namespace,
+		Labels: map[string]string{
+			v1alpha1.BarLabel:       a.bar.Name,
+			v1alpha1.ManagedByLabel: v1alpha1.ManagedByLabelValue,
+		},
+		Annotations: map[string]string{
+			WorkloadHashAnnotation: specHash,
+		},
+	}
+}
+
+// isWorkloadResourceUpToDate returns true if the existing workload resource matches the given one. Only the fields set
+// in the given resource are compared, so the ones defaulted by the API server are ignored, except for the ConfigMap
+// data, which has to match exactly.
+func isWorkloadResourceUpToDate(obj, existing client.Object) bool {
+	if !equality.Semantic.DeepDerivative(obj.GetLabels(), existing.GetLabels()) ||
+		!equality.Semantic.DeepDerivative(obj.GetAnnotations(), existing.GetAnnotations()) {
+		return false
+	}
+
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		return equality.Semantic.DeepEqual(obj.Data, existing.(*corev1.ConfigMap).Data)
+	case *appsv1.Deployment:
+		return equality.Semantic.DeepDerivative(obj.Spec, existing.(*appsv1.Deployment).Spec)
+	default:
+		return false
+	}
+}
+
+// hash returns a short hash of the JSON representation of the given value.
+func hash(value any) string {
+	data, _ := json.Marshal(value)
+	sum := sha256.Sum256(data)
 
-	return controller.ContinueProcessing()
+	return hex.EncodeToString(sum[:])[:16]
 }
@@ -0,0 +1,462 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bar
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// managedCacheClient is a client which, like the operator cache, doesn't find the ConfigMaps and Deployments without
+// the managed-by label.
+type managedCacheClient struct {
+	client.Client
+}
+
+func (c *managedCacheClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object,
+	opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	switch obj.(type) {
+	case *corev1.ConfigMap, *appsv1.Deployment:
+		if err == nil && obj.GetLabels()[v1alpha1.ManagedByLabel] != v1alpha1.ManagedByLabelValue {
+			return errors.NewNotFound(schema.GroupResource{}, key.Name)
+		}
+	}
+
+	return err
+}
+
+var _ = Describe("Bar adapter", func() {
+	const namespace = "default"
+
+	var (
+		bar *v1alpha1.Bar
+		foo *v1alpha1.Foo
+		h   *harness.Harness
+	)
+
+	newAdapter := func(objectLoader loader.ObjectLoader) *adapter {
+		h.Reload(bar)
+		return NewAdapter(h.Context, h.Client, bar, objectLoader, h.Recorder, h.Logger)
+	}
+
+	BeforeEach(func() {
+		foo = harness.NewFoo("foo", namespace).Build()
+		bar = harness.NewBar("bar", namespace).ForFoo(foo).Build()
+	})
+
+	It("sets the Foo as the controller owner of the Bar", func() {
+		h = harness.New(foo, bar)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureOwnerReferenceIsSet)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(metav1.GetControllerOf(result.GetBar(bar.Name))).NotTo(BeNil())
+		Expect(metav1.GetControllerOf(result.GetBar(bar.Name)).Name).To(Equal(foo.Name))
+	})
+
+	It("doesn't patch Bars already controlled by the Foo", func() {
+		h = harness.New(foo)
+		h.Reload(foo)
+		bar = harness.NewBar("bar", namespace).ForFoo(foo).WithOwner(foo).Build()
+		Expect(h.Client.Create(h.Context, bar)).To(Succeed())
+		a := newAdapter(loader.NewLoader())
+		resourceVersion := bar.ResourceVersion
+
+		result := h.Run(namespace, a.EnsureOwnerReferenceIsSet)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).ResourceVersion).To(Equal(resourceVersion))
+	})
+
+	It("keeps the controller of Bars whose spec.foo was changed", func() {
+		other := harness.NewFoo("other", namespace).Build()
+		bar = harness.NewBar("bar", namespace).ForFoo(foo).WithOwner(foo).Build()
+		bar.Spec.Foo = other.Name
+		h = harness.New(foo, other, bar)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureOwnerReferenceIsSet)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(metav1.GetControllerOf(result.GetBar(bar.Name)).Name).To(Equal(foo.Name))
+	})
+
+	It("stamps the missing labels on Bars created while the webhooks were disabled", func() {
+		bar = harness.NewBar("bar", namespace).WithLabels(map[string]string{v1alpha1.ManagedByLabel: "someone-else"}).Build()
+		bar.Spec.Foo = foo.Name
+		h = harness.New(foo, bar)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureLabelsAreSet)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).Labels).To(Equal(map[string]string{
+			v1alpha1.FooLabel:       foo.Name,
+			v1alpha1.ManagedByLabel: "someone-else",
+		}))
+	})
+
+	It("requeues with an error when the Foo can't be loaded", func() {
+		h = harness.New(bar)
+		a := newAdapter(loader.NewMockLoader().
+			OnGetFoo().WithArgs(foo.Name, namespace).Return(nil, fmt.Errorf("connection refused")))
+
+		result := h.Run(namespace, a.EnsureOwnerReferenceIsSet)
+		Expect(result.Err).To(MatchError("connection refused"))
+		Expect(metav1.GetControllerOf(result.GetBar(bar.Name))).To(BeNil())
+	})
+
+	Context("when the Foo doesn't exist", func() {
+		It("marks the Bar as orphaned and stops processing without requeueing", func() {
+			h = harness.New(bar)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureOrphanIsHandled, a.EnsureOwnerReferenceIsSet)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Result).To(Equal(ctrl.Result{}))
+			Expect(result.GetBar(bar.Name).IsOrphaned()).To(BeTrue())
+			Expect(h.Recorder.Events).To(Receive(ContainSubstring(OrphanedEventReason)))
+		})
+
+		It("requeues the Bar until the grace period ends with the Delete policy", func() {
+			h = harness.New(bar)
+			a := newAdapter(loader.NewLoader())
+			a.orphanPolicy = operatorconfig.OrphanPolicyDelete
+			a.orphanGracePeriod = time.Hour
+
+			result := h.Run(namespace, a.EnsureOrphanIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+			Expect(result.GetBar(bar.Name)).NotTo(BeNil())
+		})
+
+		It("deletes the Bar after the grace period with the Delete policy", func() {
+			bar.MarkOrphaned()
+			bar.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			h = harness.New(bar)
+			a := newAdapter(loader.NewLoader())
+			a.orphanPolicy = operatorconfig.OrphanPolicyDelete
+			a.orphanGracePeriod = time.Hour
+
+			result := h.Run(namespace, a.EnsureOrphanIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(bar.Name)).To(BeNil())
+			Expect(h.Recorder.Events).To(Receive(ContainSubstring(OrphanDeletedEventReason)))
+		})
+	})
+
+	It("adopts an orphaned Bar when its Foo exists again", func() {
+		bar.MarkOrphaned()
+		h = harness.New(foo, bar)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureOrphanIsHandled, a.EnsureOwnerReferenceIsSet)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).IsOrphaned()).To(BeFalse())
+		Expect(metav1.GetControllerOf(result.GetBar(bar.Name)).Name).To(Equal(foo.Name))
+		Expect(h.Recorder.Events).To(Receive(ContainSubstring(AdoptedEventReason)))
+	})
+
+	It("adds the drain finalizer", func() {
+		h = harness.New(foo, bar)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureFinalizerIsAdded)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).Finalizers).To(ContainElement(defaultDrainFinalizerName))
+	})
+
+	It("removes the drain finalizer when the Foo gives no time to drain", func() {
+		var gracePeriod int64
+		foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		bar.Finalizers = []string{defaultDrainFinalizerName}
+		h = harness.New(foo, bar)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureFinalizerIsAdded)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).Finalizers).To(BeEmpty())
+	})
+
+	It("replaces the default drain finalizer when a different finalizer domain is configured", func() {
+		bar.Finalizers = []string{defaultDrainFinalizerName}
+		h = harness.New(foo, bar)
+		a := newAdapter(loader.NewLoader())
+		a.drainFinalizerName = "example.com/drain"
+
+		result := h.Run(namespace, a.EnsureFinalizerIsAdded)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).Finalizers).To(ConsistOf("example.com/drain"))
+	})
+
+	Context("when the Bar is being deleted", func() {
+		BeforeEach(func() {
+			var gracePeriod int64 = 60
+			foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+			bar.Finalizers = []string{defaultDrainFinalizerName}
+		})
+
+		deleteBar := func() {
+			h = harness.New(foo, bar)
+			Expect(h.Client.Delete(h.Context, bar)).To(Succeed())
+		}
+
+		It("asks the Bar to drain for the termination grace period of its Foo", func() {
+			deleteBar()
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureDrainIsFinished, a.EnsureOrphanIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(result.GetBar(bar.Name).IsDraining()).To(BeTrue())
+		})
+
+		It("releases the Bar once it acknowledges the drain request", func() {
+			bar.Annotations = map[string]string{v1alpha1.DrainedAnnotation: "true"}
+			deleteBar()
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureDrainIsFinished)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(bar.Name)).To(BeNil())
+		})
+
+		It("releases the Bar once its drain deadline is reached", func() {
+			bar.MarkDraining(time.Now().Add(-time.Second))
+			deleteBar()
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureDrainIsFinished)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(bar.Name)).To(BeNil())
+		})
+
+		It("releases the Bar right away when the Foo gives no time to drain", func() {
+			var gracePeriod int64
+			foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+			deleteBar()
+			h.Reload(bar)
+			counter := harness.NewCountingClient(h.Client)
+			a := NewAdapter(h.Context, counter, bar, loader.NewLoader(), h.Recorder, h.Logger)
+
+			result := h.Run(namespace, a.EnsureDrainIsFinished)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(bar.Name)).To(BeNil())
+			Expect(counter.Count("status/patch")).To(BeZero())
+		})
+
+		It("releases the Bar still containing the default drain finalizer", func() {
+			bar.MarkDraining(time.Now().Add(-time.Second))
+			deleteBar()
+			a := newAdapter(loader.NewLoader())
+			a.drainFinalizerName = "example.com/drain"
+
+			result := h.Run(namespace, a.EnsureDrainIsFinished)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(bar.Name)).To(BeNil())
+		})
+	})
+
+	Context("when the Bar describes a workload", func() {
+		var workload *v1alpha1.BarWorkload
+
+		getConfigMap := func() *corev1.ConfigMap {
+			configMap := &corev1.ConfigMap{}
+			Expect(h.Client.Get(h.Context, client.ObjectKeyFromObject(bar), configMap)).To(Succeed())
+			return configMap
+		}
+
+		getDeployment := func() *appsv1.Deployment {
+			deployment := &appsv1.Deployment{}
+			Expect(h.Client.Get(h.Context, client.ObjectKeyFromObject(bar), deployment)).To(Succeed())
+			return deployment
+		}
+
+		BeforeEach(func() {
+			workload = &v1alpha1.BarWorkload{
+				Config: map[string]string{"key": "value"},
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "bar", Image: "bar:latest"}},
+					},
+				},
+			}
+			bar = harness.NewBar("bar", namespace).ForFoo(foo).WithWorkload(workload).Build()
+		})
+
+		It("creates a ConfigMap and a Deployment controlled by the Bar", func() {
+			h = harness.New(foo, bar)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+
+			configMap := getConfigMap()
+			Expect(configMap.Data).To(Equal(workload.Config))
+			Expect(metav1.IsControlledBy(configMap, bar)).To(BeTrue())
+
+			deployment := getDeployment()
+			Expect(metav1.IsControlledBy(deployment, bar)).To(BeTrue())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+			Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue(v1alpha1.BarLabel, bar.Name))
+			Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue(v1alpha1.BarLabel, bar.Name))
+			Expect(deployment.Spec.Template.Annotations).To(HaveKey(ConfigHashAnnotation))
+
+			Expect(result.GetBar(bar.Name).IsWorkloadReady()).To(BeFalse())
+			Expect(result.GetBar(bar.Name).Status.Workload.Replicas).To(Equal(int32(1)))
+		})
+
+		It("updates the workload only when the Bar spec changes", func() {
+			h = harness.New(foo, bar)
+			h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+			resourceVersion := getDeployment().ResourceVersion
+
+			result := h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(getDeployment().ResourceVersion).To(Equal(resourceVersion))
+
+			hash := getDeployment().Spec.Template.Annotations[ConfigHashAnnotation]
+			h.Reload(bar)
+			bar.Spec.Workload.Config["key"] = "other"
+			Expect(h.Client.Update(h.Context, bar)).To(Succeed())
+
+			result = h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(getConfigMap().Data).To(HaveKeyWithValue("key", "other"))
+			Expect(getDeployment().Spec.Template.Annotations[ConfigHashAnnotation]).NotTo(Equal(hash))
+		})
+
+		It("reverts the changes made to the workload resources", func() {
+			h = harness.New(foo, bar)
+			h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+
+			configMap := getConfigMap()
+			configMap.Data["key"] = "edited"
+			Expect(h.Client.Update(h.Context, configMap)).To(Succeed())
+			deployment := getDeployment()
+			replicas := int32(3)
+			deployment.Spec.Replicas = &replicas
+			deployment.Spec.Template.Spec.Containers[0].Image = "edited:latest"
+			Expect(h.Client.Update(h.Context, deployment)).To(Succeed())
+
+			result := h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(getConfigMap().Data).To(Equal(workload.Config))
+			Expect(*getDeployment().Spec.Replicas).To(Equal(int32(1)))
+			Expect(getDeployment().Spec.Template.Spec.Containers[0].Image).To(Equal("bar:latest"))
+		})
+
+		It("reconciles the workload of orphaned Bars", func() {
+			bar.Spec.Foo = "missing"
+			h = harness.New(bar)
+			c := &Controller{client: h.Client, log: *h.Logger, recorder: h.Recorder}
+
+			_, err := c.Reconcile(h.Context, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bar)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getDeployment().Spec.Template.Spec.Containers[0].Image).To(Equal("bar:latest"))
+			h.Reload(bar)
+			Expect(bar.IsOrphaned()).To(BeTrue())
+		})
+
+		It("marks the workload as ready when all the replicas are updated and available", func() {
+			h = harness.New(foo, bar)
+			h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+
+			deployment := getDeployment()
+			deployment.Status.ObservedGeneration = deployment.Generation
+			deployment.Status.UpdatedReplicas = 1
+			deployment.Status.AvailableReplicas = 1
+			Expect(h.Client.Status().Update(h.Context, deployment)).To(Succeed())
+
+			result := h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(bar.Name).IsWorkloadReady()).To(BeTrue())
+			Expect(result.GetBar(bar.Name).Status.Workload.AvailableReplicas).To(Equal(int32(1)))
+		})
+
+		It("doesn't take over resources not controlled by the Bar", func() {
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: bar.Name, Namespace: namespace}}
+			h = harness.New(foo, bar, configMap)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Result).To(Equal(ctrl.Result{}))
+			Expect(getConfigMap().Data).To(BeEmpty())
+
+			condition := apimeta.FindStatusCondition(result.GetBar(bar.Name).Status.Conditions, "WorkloadReady")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(BeEquivalentTo(v1alpha1.WorkloadConflictReason))
+		})
+
+		It("stops without requeueing when the resources not controlled by the Bar are missing from the cache", func() {
+			deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: bar.Name, Namespace: namespace}}
+			h = harness.New(foo, bar, deployment)
+			h.Reload(bar)
+			a := NewAdapter(h.Context, &managedCacheClient{h.Client}, bar, loader.NewLoader(), h.Recorder, h.Logger)
+
+			result := h.Run(namespace, a.EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Result).To(Equal(ctrl.Result{}))
+			Expect(getDeployment().OwnerReferences).To(BeEmpty())
+
+			condition := apimeta.FindStatusCondition(result.GetBar(bar.Name).Status.Conditions, "WorkloadReady")
+			Expect(condition.Reason).To(BeEquivalentTo(v1alpha1.WorkloadConflictReason))
+			Expect(condition.Message).To(Equal("Deployment bar already exists and is not controlled by the Bar"))
+		})
+
+		It("deletes the workload when it's removed from the Bar", func() {
+			h = harness.New(foo, bar)
+			h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+
+			h.Reload(bar)
+			bar.Spec.Workload = nil
+			Expect(h.Client.Update(h.Context, bar)).To(Succeed())
+
+			result := h.Run(namespace, newAdapter(loader.NewLoader()).EnsureWorkloadIsReconciled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(h.Client.Get(h.Context, client.ObjectKeyFromObject(bar), &appsv1.Deployment{})).NotTo(Succeed())
+			Expect(h.Client.Get(h.Context, client.ObjectKeyFromObject(bar), &corev1.ConfigMap{})).NotTo(Succeed())
+			Expect(result.GetBar(bar.Name).Status.Workload).To(BeNil())
+		})
+	})
+
+	It("reports the reconcile requested with the annotation once handled", func() {
+		bar.Annotations = map[string]string{v1alpha1.ReconcileRequestedAtAnnotation: "2023-05-04T10:00:00Z"}
+		h = harness.New(foo, bar)
+
+		result := h.Run(namespace, newAdapter(loader.NewLoader()).EnsureReconcileRequestIsHandled)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar(bar.Name).Status.LastHandledReconcileAt).To(Equal("2023-05-04T10:00:00Z"))
+	})
+})
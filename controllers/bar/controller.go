@@ -21,29 +21,54 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit-example/sharding"
+	"github.com/konflux-ci/operator-toolkit-example/tracing"
 	"github.com/konflux-ci/operator-toolkit/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
 
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // BarReconciler reconciles a Bar object
 type Controller struct {
-	client client.Client
-	log    logr.Logger
+	client                  client.Client
+	drainFinalizerName      string
+	log                     logr.Logger
+	maxConcurrentReconciles int
+	namespaces              operatorconfig.NamespaceFilter
+	orphans                 operatorconfig.OrphansConfig
+	recorder                record.EventRecorder
+	sharder                 *sharding.Sharder
 }
 
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=bars,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=bars/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=bars/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := c.log.WithValues("Bar", req.NamespacedName)
+	ctx, span := tracing.StartReconcile(ctx, "Bar", req.Namespace, req.Name)
+	defer span.End()
+
+	logger := tracing.WithTraceID(ctx, c.log.WithValues("Bar", req.NamespacedName))
 
 	bar := &v1alpha1.Bar{}
 	err := c.client.Get(ctx, req.NamespacedName, bar)
@@ -55,19 +80,120 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
-	adapter := NewAdapter(ctx, c.client, bar, loader.NewLoader(), &logger)
+	// The bucket of the Foo might have been handed over to another replica after the request was queued
+	if !c.sharder.Owns(bar) {
+		return ctrl.Result{}, nil
+	}
 
-	return controller.ReconcileHandler([]controller.Operation{
+	adapter := NewAdapter(ctx, c.client, bar, loader.NewLoader(), c.recorder, &logger)
+	if c.orphans.Policy != "" {
+		adapter.orphanGracePeriod = c.orphans.GracePeriod.Duration
+		adapter.orphanPolicy = c.orphans.Policy
+	}
+	if c.drainFinalizerName != "" {
+		adapter.drainFinalizerName = c.drainFinalizerName
+	}
+
+	return controller.ReconcileHandler(tracing.TraceOperations(&adapter.Context,
+		adapter.EnsureDrainIsFinished,
+		adapter.EnsureFinalizerIsAdded,
+		adapter.EnsureWorkloadIsReconciled,
+		adapter.EnsureOrphanIsHandled,
+		adapter.EnsureLabelsAreSet,
 		adapter.EnsureOwnerReferenceIsSet,
-	})
+		adapter.EnsureReconcileRequestIsHandled,
+	))
+}
+
+// Configure applies the Bar controller configuration, the drain finalizer, the watched namespaces and the orphan
+// policy set in the operator configuration.
+func (c *Controller) Configure(config *operatorconfig.OperatorConfig) {
+	c.drainFinalizerName = config.GetDrainFinalizerName()
+	c.maxConcurrentReconciles = config.GetControllerConfig(operatorconfig.BarController).MaxConcurrentReconciles
+	c.namespaces = config.GetNamespaceFilter()
+	c.orphans = config.Orphans
+}
+
+// SetSharder sets the Sharder used to reconcile only the Bar resources whose Foo is in the buckets held by the
+// replica.
+func (c *Controller) SetSharder(sharder *sharding.Sharder) {
+	c.sharder = sharder
 }
 
 // Register registers the controller with the passed manager and log.
 func (c *Controller) Register(mgr ctrl.Manager, log *logr.Logger, _ cluster.Cluster) error {
-	c.client = mgr.GetClient()
-	c.log = log.WithName("bar")
+	c.client = tracing.NewClient(mgr.GetClient())
+	c.log = log.WithName(operatorconfig.BarController)
+	c.recorder = mgr.GetEventRecorderFor("bar-controller")
+
+	// The namespace predicate is not an event filter, as it would drop the bucket leases living in the namespace of the
+	// operator
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Bar{}, builder.WithPredicates(c.namespaces.Predicate())).
+		Owns(&appsv1.Deployment{}, builder.WithPredicates(c.namespaces.Predicate())).
+		Owns(&corev1.ConfigMap{}, builder.WithPredicates(c.namespaces.Predicate())).
+		WithEventFilter(c.sharder.Predicate()).
+		WithOptions(crcontroller.Options{MaxConcurrentReconciles: c.maxConcurrentReconciles})
+
+	// Orphaned Bars are adopted as soon as their Foo is created unless they have to wait to be reconciled again
+	if c.orphans.Policy != operatorconfig.OrphanPolicyWait {
+		controllerBuilder = controllerBuilder.Watches(&source.Kind{Type: &v1alpha1.Foo{}},
+			handler.EnqueueRequestsFromMapFunc(c.mapFooToBars),
+			builder.WithPredicates(c.namespaces.Predicate(), predicate.Funcs{
+				DeleteFunc: func(event.DeleteEvent) bool {
+					return false
+				},
+				GenericFunc: func(event.GenericEvent) bool {
+					return false
+				},
+				UpdateFunc: func(event.UpdateEvent) bool {
+					return false
+				},
+			}))
+	}
+
+	// The Bar resources in a bucket are reconciled when the replica takes it over
+	if c.sharder != nil {
+		controllerBuilder = controllerBuilder.Watches(c.sharder.Source(),
+			handler.EnqueueRequestsFromMapFunc(c.mapLeaseToBars))
+	}
+
+	return controllerBuilder.Complete(c)
+}
+
+// mapFooToBars returns a reconcile request for every orphaned Bar resource belonging to the given Foo resource.
+func (c *Controller) mapFooToBars(foo client.Object) []reconcile.Request {
+	bars, err := loader.NewLoader().GetNamespaceBars(context.Background(), c.client, foo.GetNamespace())
+	if err != nil {
+		c.log.Error(err, "unable to list the Bar resources of the created Foo")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range bars {
+		if bars[i].Spec.Foo == foo.GetName() && bars[i].IsOrphaned() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&bars[i])})
+		}
+	}
+
+	return requests
+}
+
+// mapLeaseToBars returns a reconcile request for every Bar resource whose Foo is in the bucket of the given lease.
+func (c *Controller) mapLeaseToBars(lease client.Object) []reconcile.Request {
+	bars := &v1alpha1.BarList{}
+	err := c.client.List(context.Background(), bars)
+	if err != nil {
+		c.log.Error(err, "unable to list the Bar resources of the acquired bucket")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range bars.Items {
+		if c.sharder.InBucket(&bars.Items[i], lease) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&bars.Items[i])})
+		}
+	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Bar{}).
-		Complete(c)
+	return requests
 }
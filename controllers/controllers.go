@@ -3,11 +3,45 @@ package controllers
 import (
 	"github.com/konflux-ci/operator-toolkit-example/controllers/bar"
 	"github.com/konflux-ci/operator-toolkit-example/controllers/foo"
+	"github.com/konflux-ci/operator-toolkit-example/controllers/fooquota"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit-example/sharding"
 	"github.com/konflux-ci/operator-toolkit/controller"
 )
 
-// EnabledControllers is a slice containing references to all the controllers that have to be registered
-var EnabledControllers = []controller.Controller{
-	&bar.Controller{},
-	&foo.Controller{},
+// AllControllers is a map containing references to all the controllers, keyed by the name used to enable them
+var AllControllers = map[string]controller.Controller{
+	operatorconfig.BarController:      &bar.Controller{},
+	operatorconfig.FooController:      &foo.Controller{},
+	operatorconfig.FooQuotaController: &fooquota.Controller{},
+}
+
+// EnabledControllers returns the controllers enabled in the operator configuration, sorted by name.
+func EnabledControllers(config *operatorconfig.OperatorConfig) []controller.Controller {
+	var enabledControllers []controller.Controller
+	for _, name := range config.GetEnabledControllers() {
+		enabledControllers = append(enabledControllers, AllControllers[name])
+	}
+
+	return enabledControllers
+}
+
+// Configure passes the operator configuration to the enabled controllers implementing operatorconfig.Configurable.
+// It has to be called before setting up the controllers.
+func Configure(config *operatorconfig.OperatorConfig) {
+	for _, enabledController := range EnabledControllers(config) {
+		if configurable, ok := enabledController.(operatorconfig.Configurable); ok {
+			configurable.Configure(config)
+		}
+	}
+}
+
+// SetSharder passes the given Sharder to the controllers implementing sharding.Shardable. It has to be called before
+// setting up the controllers.
+func SetSharder(sharder *sharding.Sharder) {
+	for _, name := range operatorconfig.ControllerNames {
+		if shardable, ok := AllControllers[name].(sharding.Shardable); ok {
+			shardable.SetSharder(sharder)
+		}
+	}
 }
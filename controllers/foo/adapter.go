@@ -2,108 +2,303 @@ package foo
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/adapters"
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/finalizers"
 	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/metrics"
+	"github.com/konflux-ci/operator-toolkit-example/multicluster"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit-example/tracing"
 	"github.com/konflux-ci/operator-toolkit/controller"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// Reasons of the events recorded for Foo resources.
+const (
+	// DriftCorrectedEventReason is the reason of the event recorded when drifted Bar resources are restored
+	DriftCorrectedEventReason = "DriftCorrected"
+
+	// FinalizationTimedOutEventReason is the reason of the event recorded when the finalizer of a Foo resource is
+	// removed before all its Bar resources are gone
+	FinalizationTimedOutEventReason = "FinalizationTimedOut"
+
+	// UnknownFinalizerRemovedEventReason is the reason of the event recorded when a finalizer added by the operator
+	// for a finalizer handler which isn't registered anymore is removed from a Foo resource
+	UnknownFinalizerRemovedEventReason = "UnknownFinalizerRemoved"
+)
+
 // Adapter holds the objects needed to reconcile a Foo resource.
 type adapter struct {
+	adapters.Adapter[*v1alpha1.Foo, loader.ObjectLoader]
+	drainFinalizerName  string
+	enforceQuotas       bool
+	finalizationTimeout time.Duration
+	finalizerDomain     string
+	finalizerName       string
+	finalizers          *finalizers.Registry
+	foo                 *v1alpha1.Foo
+	hub                 *multicluster.Hub
+	maxReplicaStatuses  int
+	recorder            record.EventRecorder
+}
+
+// placementTarget is a cluster the Bar resources of a Foo resource are placed into.
+type placementTarget struct {
 	client client.Client
-	ctx    context.Context
-	foo    *v1alpha1.Foo
-	loader loader.ObjectLoader
-	logger *logr.Logger
+	name   string
+}
+
+// newMemberTarget returns the placementTarget of the given member cluster. Its client is a loader.ClusterClient, so
+// the objects loaded from every cluster are memoized apart.
+func newMemberTarget(name string, member cluster.Cluster) placementTarget {
+	return placementTarget{client: loader.NewClusterClient(name, tracing.NewClient(member.GetClient())), name: name}
+}
+
+// isLocal returns true if the target is the cluster the Foo resource lives in.
+func (t placementTarget) isLocal() bool {
+	return t.name == ""
 }
 
-// NewAdapter creates and returns an Adapter instance.
-func NewAdapter(ctx context.Context, client client.Client, foo *v1alpha1.Foo, loader loader.ObjectLoader, logger *logr.Logger) *adapter {
+// qualifiedName returns the given resource name prefixed with the name of the target, unless it is the local one.
+func (t placementTarget) qualifiedName(name string) string {
+	if t.isLocal() {
+		return name
+	}
+
+	return t.name + "/" + name
+}
+
+// NewAdapter creates and returns an Adapter instance. The hub is only needed when running in multi-cluster mode and
+// can be nil otherwise. The adapter uses the default finalizer domain, names and timeout, the handlers in the default
+// finalizer registry and the default maximum number of replica statuses, and enforces the FooQuota resources, unless
+// its finalizerDomain, finalizerName, drainFinalizerName, finalizationTimeout, finalizers, maxReplicaStatuses and
+// enforceQuotas fields are changed.
+func NewAdapter(ctx context.Context, client client.Client, foo *v1alpha1.Foo, hub *multicluster.Hub, loader loader.ObjectLoader,
+	recorder record.EventRecorder, logger *logr.Logger) *adapter {
+	defaults := operatorconfig.DefaultConfig()
+
 	return &adapter{
-		client: client,
-		ctx:    ctx,
-		foo:    foo,
-		loader: loader,
-		logger: logger,
+		Adapter:             adapters.New(ctx, client, foo, loader, logger),
+		drainFinalizerName:  defaultDrainFinalizerName,
+		enforceQuotas:       defaults.IsFeatureEnabled(operatorconfig.FooQuotasFeature),
+		finalizationTimeout: defaults.FinalizationTimeout.Duration,
+		finalizerDomain:     defaults.FinalizerDomain,
+		finalizerName:       defaultFinalizerName,
+		finalizers:          finalizers.DefaultRegistry,
+		foo:                 foo,
+		hub:                 hub,
+		maxReplicaStatuses:  defaults.MaxReplicaStatuses,
+		recorder:            recorder,
 	}
 }
 
-// finalizerName is the finalizer name to be added to the Foo resource
-const finalizerName string = "appstudio.redhat.com/finalizer"
+// defaultFinalizerName is the finalizer name to be added to the Foo resource when no finalizer domain is configured.
+// Foo resources still containing it once a different domain is configured get it replaced by the configured one.
+const defaultFinalizerName string = "appstudio.redhat.com/finalizer"
+
+// defaultDrainFinalizerName is the drain finalizer added to the Bar resources when no finalizer domain is configured.
+// The Bar controller replaces it by the configured one, so it is only removed here from the Bars in member clusters.
+const defaultDrainFinalizerName string = "appstudio.redhat.com/drain"
 
 // EnsureFinalizersAreCalled is an operation that will ensure that finalizers are called whenever the Foo resource being
-// processed is marked for deletion. Once finalizers get called, the finalizer will be removed and the Foo resource will go
-// back to the queue, so it gets deleted. If a finalizer function fails its execution or a finalizer fails to be removed,
-// the Foo resource will be requeued with the error attached.
+// processed is marked for deletion. The finalizers added for a finalizer handler which isn't registered anymore, e.g.
+// because the feature registering it was removed, are removed first with a warning event. Only the ones recorded in
+// the HandlerFinalizersAnnotation are, as others might use the same domain. The registered finalizer
+// handlers run next, each one removing its own finalizer when it succeeds and reporting its failures in the status
+// otherwise. The finalizer is only removed once all the Bar resources are gone, so the Foo resource is marked as
+// terminating until then. Both the failing handlers and the Bar resources are given up on once the finalization
+// timeout is reached, in which case a warning event is recorded. The Foo resource will then go back to the queue, so
+// it gets deleted. If a finalizer function fails its execution or a finalizer fails to be removed, the Foo resource
+// will be requeued with the error attached.
 func (a *adapter) EnsureFinalizersAreCalled() (controller.OperationResult, error) {
-	// Check if the Foo resource is marked for deletion and continue processing other operations otherwise
-	if a.foo.GetDeletionTimestamp() == nil {
-		return controller.ContinueProcessing()
+	var finalizerList []adapters.Finalizer
+	for _, finalizer := range a.getUnknownFinalizers() {
+		finalizerList = append(finalizerList, adapters.Finalizer{
+			Name:     finalizer,
+			Finalize: a.newUnknownFinalize(finalizer),
+		})
+	}
+
+	for _, domain := range a.getFinalizerDomains() {
+		for _, name := range a.finalizers.GetNames() {
+			finalizerList = append(finalizerList, adapters.Finalizer{
+				Name:     finalizers.GetFinalizerName(domain, name),
+				Finalize: a.newHandlerFinalize(name),
+			})
+		}
 	}
 
-	if controllerutil.ContainsFinalizer(a.foo, finalizerName) {
-		if err := a.finalizeResource(); err != nil {
+	finalizerList = append(finalizerList, adapters.Finalizer{
+		Name:     a.finalizerName,
+		Finalize: a.finalizeBars,
+	})
+
+	// Foo resources added before the finalizer domain was configured are still cleaned up with the default finalizer
+	if a.finalizerName != defaultFinalizerName {
+		finalizerList = append(finalizerList, adapters.Finalizer{
+			Name:     defaultFinalizerName,
+			Finalize: a.finalizeBars,
+		})
+	}
+
+	return a.RunFinalizers(finalizerList...)
+}
+
+// EnsureFinalizerIsAdded is an operation that will ensure that the Foo resource being processed contains a finalizer,
+// along with the finalizer of every registered finalizer handler. The handler finalizers are recorded in the
+// HandlerFinalizersAnnotation before being added, so they can be removed once their handler isn't registered anymore.
+// The default finalizer is replaced by the configured one when a different finalizer domain is used, as nothing would
+// remove it otherwise.
+func (a *adapter) EnsureFinalizerIsAdded() (controller.OperationResult, error) {
+	if a.finalizerName != defaultFinalizerName && controllerutil.ContainsFinalizer(a.foo, defaultFinalizerName) {
+		a.Logger.Info("Replacing the default finalizer", "Finalizer", a.finalizerName)
+		err := a.Patch(func() {
+			controllerutil.RemoveFinalizer(a.foo, defaultFinalizerName)
+			controllerutil.AddFinalizer(a.foo, a.finalizerName)
+		})
+		if err != nil {
 			return controller.RequeueWithError(err)
 		}
+	}
 
-		patch := client.MergeFrom(a.foo.DeepCopy())
-		controllerutil.RemoveFinalizer(a.foo, finalizerName)
-		err := a.client.Patch(a.ctx, a.foo, patch)
+	handlerFinalizers := a.getHandlerFinalizerNames()
+	if a.foo.DeepCopy().RecordHandlerFinalizers(handlerFinalizers...) {
+		err := a.Patch(func() {
+			a.foo.RecordHandlerFinalizers(handlerFinalizers...)
+		})
 		if err != nil {
 			return controller.RequeueWithError(err)
 		}
 	}
 
-	// Requeue the Foo resource again so it gets deleted and other operations are not executed
-	return controller.Requeue()
+	return a.AddFinalizers(append([]string{a.finalizerName}, handlerFinalizers...)...)
 }
 
-// EnsureFinalizerIsAdded is an operation that will ensure that the Foo resource being processed contains a finalizer.
-func (a *adapter) EnsureFinalizerIsAdded() (controller.OperationResult, error) {
-	var finalizerFound bool
-	for _, finalizer := range a.foo.GetFinalizers() {
-		if finalizer == finalizerName {
-			finalizerFound = true
+// EnsureDrainedReplicasAreDeleted is an operation that will ensure that the Bar resources being deleted in member
+// clusters are released as soon as they acknowledge the drain request or their drain deadline is reached, including
+// the ones in clusters removed from the placement. The Bar resources in the cluster the Foo resource lives in are
+// released by the Bar controller, as no Bar controller runs in member clusters. The Foo resource is requeued until
+// the next deadline if any Bar resource is still draining.
+func (a *adapter) EnsureDrainedReplicasAreDeleted() (controller.OperationResult, error) {
+	var requeueDelay time.Duration
+	for _, target := range a.getAllTargets() {
+		if target.isLocal() {
+			continue
 		}
-	}
 
-	if !finalizerFound {
-		a.logger.Info("Adding Finalizer to the Foo resource")
-		patch := client.MergeFrom(a.foo.DeepCopy())
-		controllerutil.AddFinalizer(a.foo, finalizerName)
-		err := a.client.Patch(a.ctx, a.foo, patch)
+		replicas, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
 
-		return controller.RequeueOnErrorOrContinue(err)
+		targetRequeueDelay, err := a.releaseDrainedBars(target, replicas)
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+		requeueDelay = minRequeueDelay(requeueDelay, targetRequeueDelay)
+	}
+
+	if requeueDelay > 0 {
+		return controller.RequeueAfter(requeueDelay, nil)
 	}
 
 	return controller.ContinueProcessing()
 }
 
 // EnsureMaximumReplicas is an operation that will ensure that the number of replicas for this resource doesn't go beyond
-// the desired number of replicas, deleting Bar resources if needed.
+// the desired number of replicas, draining and deleting Bar resources if needed.
 func (a *adapter) EnsureMaximumReplicas() (controller.OperationResult, error) {
-	replicas, err := a.loader.GetBars(a.ctx, a.client, a.foo)
+	targets, err := a.getPlacementTargets()
 	if err != nil {
 		return controller.RequeueWithError(err)
 	}
 
-	replicasDelta := a.foo.Spec.DesiredReplicas - len(replicas)
+	for _, target := range targets {
+		replicas, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+		replicas = getActiveBars(replicas)
 
-	if replicasDelta >= 0 {
-		return controller.ContinueProcessing()
+		replicasDelta := a.foo.Spec.DesiredReplicas - len(replicas)
+
+		if replicasDelta >= 0 {
+			continue
+		}
+
+		// Scaling to zero removes all the Bar resources, so they are deleted at once
+		if a.foo.Spec.DesiredReplicas == 0 {
+			err = a.deleteAllBars(target, replicas)
+			if err != nil {
+				return controller.RequeueWithError(err)
+			}
+			loader.Invalidate(a.Loader)
+
+			continue
+		}
+
+		for i := range replicas[a.foo.Spec.DesiredReplicas:] {
+			replica := &replicas[a.foo.Spec.DesiredReplicas+i]
+			err := a.drainBar(target, replica)
+			if err != nil && !errors.IsNotFound(err) {
+				return controller.RequeueWithError(err)
+			}
+			a.Logger.Info("Bar deleted", "Bar.Name", replica.Name, "Bar.Namespace", replica.Namespace,
+				"Cluster", target.name)
+		}
+
+		loader.Invalidate(a.Loader)
 	}
 
-	for _, replica := range replicas[a.foo.Spec.DesiredReplicas:] {
-		err := a.client.Delete(a.ctx, &replica)
-		if err != nil && errors.IsNotFound(err) {
+	return controller.ContinueProcessing()
+}
+
+// EnsureUnplacedReplicasAreDeleted is an operation that will ensure that the Bar resources in clusters removed from
+// the placement of the Foo resource are drained and deleted. Member clusters are no longer recorded in the status once
+// their Bar resources are gone.
+func (a *adapter) EnsureUnplacedReplicasAreDeleted() (controller.OperationResult, error) {
+	for _, target := range a.getRemovedTargets() {
+		replicas, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
 			return controller.RequeueWithError(err)
 		}
-		a.logger.Info("Bar deleted", "Bar.Name", replica.Name, "Bar.Namespace", replica.Namespace)
+
+		if len(replicas) > 0 {
+			err = a.deleteAllBars(target, replicas)
+			if err != nil {
+				return controller.RequeueWithError(err)
+			}
+			loader.Invalidate(a.Loader)
+
+			continue
+		}
+
+		if !target.isLocal() {
+			err = a.PatchStatus(func() {
+				a.foo.RemovePlacedCluster(target.name)
+			})
+			if err != nil {
+				return controller.RequeueWithError(err)
+			}
+			a.Logger.Info("Member cluster removed from the placement cleaned up", "Cluster", target.name)
+		}
 	}
 
 	return controller.ContinueProcessing()
@@ -112,82 +307,796 @@ func (a *adapter) EnsureMaximumReplicas() (controller.OperationResult, error) {
 // EnsureMinimumReplicas is an operation that will ensure that the number of replicas for this resource doesn't go below
 // the desired number of replicas, creating Bar resources if needed.
 func (a *adapter) EnsureMinimumReplicas() (controller.OperationResult, error) {
-	replicas, err := a.loader.GetBars(a.ctx, a.client, a.foo)
+	targets, err := a.getPlacementTargets()
 	if err != nil {
 		return controller.RequeueWithError(err)
 	}
 
-	replicasDelta := a.foo.Spec.DesiredReplicas - len(replicas)
-
-	if replicasDelta < 0 {
-		return controller.ContinueProcessing()
+	// Member clusters are recorded before creating Bars in them, so the Bars are deleted if the cluster is removed from
+	// the placement
+	err = a.recordPlacedClusters(targets)
+	if err != nil {
+		return controller.RequeueWithError(err)
 	}
 
-	for i := 0; i < replicasDelta; i++ {
-		replica := &v1alpha1.Bar{
-			ObjectMeta: v1.ObjectMeta{
-				GenerateName: a.foo.Name + "-",
-				Namespace:    a.foo.Namespace,
-			},
-			Spec: v1alpha1.BarSpec{
-				Foo: a.foo.Name,
-			},
+	for _, target := range targets {
+		replicas, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return controller.RequeueWithError(err)
 		}
+		replicas = getActiveBars(replicas)
+
+		replicasDelta := a.foo.Spec.DesiredReplicas - len(replicas)
 
-		err := a.client.Create(a.ctx, replica)
+		if replicasDelta < 0 {
+			continue
+		}
+
+		allowedReplicas, err := a.getAllowedReplicas(target, len(replicas))
 		if err != nil {
 			return controller.RequeueWithError(err)
 		}
-		a.logger.Info("Bar created", "Bar.Name", replica.Name, "Bar.Namespace", replica.Namespace)
+
+		if allowedReplicas < a.foo.Spec.DesiredReplicas {
+			a.Logger.Info("Scale up limited by FooQuota", "DesiredReplicas", a.foo.Spec.DesiredReplicas,
+				"AllowedReplicas", allowedReplicas, "Cluster", target.name)
+			replicasDelta = allowedReplicas - len(replicas)
+		}
+
+		for i := 0; i < replicasDelta; i++ {
+			replica := a.newBarTemplate()
+			replica.Annotations = map[string]string{v1alpha1.TemplateHashAnnotation: hashBarTemplate(replica, replica)}
+			replica.Labels[v1alpha1.FooUIDLabel] = string(a.foo.UID)
+			replica.GenerateName = a.foo.Name + "-"
+			replica.Namespace = a.foo.Namespace
+			if a.foo.GetTerminationGracePeriod() > 0 {
+				replica.Finalizers = []string{a.drainFinalizerName}
+			}
+
+			err := target.client.Create(a.Context, replica)
+			if err != nil {
+				return controller.RequeueWithError(err)
+			}
+			a.Logger.Info("Bar created", "Bar.Name", replica.Name, "Bar.Namespace", replica.Namespace,
+				"Cluster", target.name)
+		}
+
+		if replicasDelta > 0 {
+			loader.Invalidate(a.Loader)
+		}
 	}
 
 	return controller.ContinueProcessing()
 }
 
+// EnsureDriftIsHandled is an operation that will ensure that the Bar replicas whose labels or Foo reference don't
+// match the Foo resource anymore, or whose template hash annotation doesn't match the current template, are reported
+// in the DriftDetected condition or, with the Correct drift policy, restored. Bar replicas without the annotation,
+// e.g. created before it existed, are only compared by their fields.
+func (a *adapter) EnsureDriftIsHandled() (controller.OperationResult, error) {
+	targets, err := a.getPlacementTargets()
+	if err != nil {
+		return controller.RequeueWithError(err)
+	}
+
+	template := a.newBarTemplate()
+	templateHash := hashBarTemplate(template, template)
+
+	driftedBars := []string{}
+	for _, target := range targets {
+		replicas, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+
+		for _, replica := range getActiveBars(replicas) {
+			replicaHash, annotated := replica.Annotations[v1alpha1.TemplateHashAnnotation]
+			if hashBarTemplate(&replica, template) == templateHash && (!annotated || replicaHash == templateHash) {
+				continue
+			}
+
+			driftedBars = append(driftedBars, target.qualifiedName(replica.Name))
+			if a.foo.GetDriftPolicy() != v1alpha1.DriftPolicyCorrect {
+				continue
+			}
+
+			patch := client.MergeFrom(replica.DeepCopy())
+			if replica.Labels == nil {
+				replica.Labels = map[string]string{}
+			}
+			for key, value := range template.Labels {
+				replica.Labels[key] = value
+			}
+			if replica.Annotations == nil {
+				replica.Annotations = map[string]string{}
+			}
+			replica.Annotations[v1alpha1.TemplateHashAnnotation] = templateHash
+			replica.Spec.Foo = template.Spec.Foo
+			if template.Spec.Workload != nil {
+				replica.Spec.Workload = template.Spec.Workload.DeepCopy()
+			}
+			err = target.client.Patch(a.Context, &replica, patch)
+			if err != nil && !errors.IsNotFound(err) {
+				return controller.RequeueWithError(err)
+			}
+			a.Logger.Info("Drifted Bar corrected", "Bar.Name", replica.Name, "Bar.Namespace", replica.Namespace,
+				"Cluster", target.name)
+		}
+	}
+
+	if len(driftedBars) > 0 && a.foo.GetDriftPolicy() == v1alpha1.DriftPolicyCorrect {
+		loader.Invalidate(a.Loader)
+		a.recorder.Eventf(a.foo, corev1.EventTypeNormal, DriftCorrectedEventReason, "Corrected %d Bar resources: %s",
+			len(driftedBars), strings.Join(driftedBars, ", "))
+	}
+
+	return controller.RequeueOnErrorOrContinue(a.PatchStatus(func() {
+		switch {
+		case len(driftedBars) == 0:
+			a.foo.MarkNoDrift()
+		case a.foo.GetDriftPolicy() == v1alpha1.DriftPolicyCorrect:
+			a.foo.MarkDriftCorrected(driftedBars)
+		default:
+			a.foo.MarkDriftDetected(driftedBars)
+		}
+	}))
+}
+
+// EnsureReconcileRequestIsHandled is an operation that will ensure that the value of the requested-at annotation of
+// the Foo resource is reported in its status once its Bar replicas and status are up to date, so whoever requested
+// the reconcile can tell it has been handled.
+func (a *adapter) EnsureReconcileRequestIsHandled() (controller.OperationResult, error) {
+	return a.MarkReconcileRequestHandled(v1alpha1.ReconcileRequestedAtAnnotation)
+}
+
+// EnsureResyncIsScheduled is an operation that will ensure that the Foo resource is reconciled again after its resync
+// period, if set, so Bar replicas modified without triggering a reconcile are eventually detected.
+func (a *adapter) EnsureResyncIsScheduled() (controller.OperationResult, error) {
+	if a.foo.Spec.ResyncPeriod == nil || a.foo.Spec.ResyncPeriod.Duration <= 0 {
+		return controller.ContinueProcessing()
+	}
+
+	return controller.RequeueAfter(a.foo.Spec.ResyncPeriod.Duration, nil)
+}
+
 // EnsureReplicaDataConsistency is an operation that will ensure that the list of replicas in the Foo resource's status
-// is kept up to date. It will also update the health condition type when needed.
+// is kept up to date, along with the FooReplicas metric. It will also update the health condition type when needed.
 func (a *adapter) EnsureReplicaDataConsistency() (controller.OperationResult, error) {
-	replicas, err := a.loader.GetBars(a.ctx, a.client, a.foo)
+	targets, err := a.getPlacementTargets()
 	if err != nil {
 		return controller.RequeueWithError(err)
 	}
 
+	template := a.newBarTemplate()
+
 	patch := client.MergeFrom(a.foo.DeepCopy())
 	a.foo.Status.Replicas = []string{}
+	a.foo.Status.ReplicaDetails = nil
+	a.foo.Status.OmittedReplicas = 0
+	a.foo.Status.Clusters = nil
+	a.foo.Status.TemplateRevision = hashBarTemplate(template, template)
+
+	// Missing replicas in any cluster make the resource unhealthy, so they take precedence over the extra ones
+	var minReplicasDelta, maxReplicasDelta, totalReplicas int
+	var quotaExceeded bool
+	for i, target := range targets {
+		replicas, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return controller.RequeueWithError(err)
+		}
+		replicas = getActiveBars(replicas)
+		totalReplicas += len(replicas)
+
+		// The deprecated list of names is limited to the same replicas as the details to keep the status small
+		replicaStatuses, omittedReplicas := a.getReplicaStatuses(replicas, template)
+		names := []string{}
+		for _, replicaStatus := range replicaStatuses {
+			names = append(names, replicaStatus.Name)
+		}
+		if target.isLocal() {
+			a.foo.Status.Replicas = names
+			a.foo.Status.ReplicaDetails = replicaStatuses
+			a.foo.Status.OmittedReplicas = omittedReplicas
+		} else {
+			a.foo.Status.Clusters = append(a.foo.Status.Clusters, v1alpha1.ClusterStatus{
+				Name:            target.name,
+				Replicas:        names,
+				ReplicaDetails:  replicaStatuses,
+				OmittedReplicas: omittedReplicas,
+			})
+		}
+
+		targetReplicasDelta := len(replicas) - a.foo.Spec.DesiredReplicas
+		if targetReplicasDelta < 0 {
+			allowedReplicas, err := a.getAllowedReplicas(target, len(replicas))
+			if err != nil {
+				return controller.RequeueWithError(err)
+			}
+			quotaExceeded = quotaExceeded || allowedReplicas < a.foo.Spec.DesiredReplicas
+		}
+		if i == 0 || targetReplicasDelta < minReplicasDelta {
+			minReplicasDelta = targetReplicasDelta
+		}
+		if i == 0 || targetReplicasDelta > maxReplicasDelta {
+			maxReplicasDelta = targetReplicasDelta
+		}
+	}
+
+	metrics.RecordFooReplicas(a.foo, totalReplicas)
 
-	for _, replica := range replicas {
-		a.foo.Status.Replicas = append(a.foo.Status.Replicas, replica.Name)
+	replicasDelta := maxReplicasDelta
+	if minReplicasDelta < 0 {
+		replicasDelta = minReplicasDelta
 	}
 
-	replicasDelta := len(replicas) - a.foo.Spec.DesiredReplicas
 	if replicasDelta == 0 {
 		a.foo.MarkHealthy(v1alpha1.HealthyReason)
 	} else if replicasDelta > 0 {
 		a.foo.MarkHealthy(v1alpha1.TooManyReplicasReason)
+	} else if quotaExceeded {
+		a.foo.MarkQuotaExceeded()
 	} else {
 		a.foo.MarkUnhealthy()
 	}
 
-	return controller.RequeueOnErrorOrContinue(a.client.Status().Patch(a.ctx, a.foo, patch))
+	return controller.RequeueOnErrorOrContinue(a.Client.Status().Patch(a.Context, a.foo, patch))
+}
+
+// getAllowedReplicas returns the number of replicas the Foo resource can scale up to in the given target without going
+// beyond the limits set by the FooQuota resources in its namespace. The FooQuota resources of the cluster the Foo
+// resource lives in apply to the namespace of the same name in every member cluster as well. The current number of
+// replicas of the Foo resource in the target is needed as they are part of the namespace usage already. The desired
+// number of replicas is allowed when the FooQuota resources are not enforced.
+func (a *adapter) getAllowedReplicas(target placementTarget, currentReplicas int) (int, error) {
+	if !a.enforceQuotas {
+		return a.foo.Spec.DesiredReplicas, nil
+	}
+
+	quotas, err := a.Loader.GetFooQuotas(a.Context, a.Client, a.foo.Namespace)
+	if err != nil || len(quotas) == 0 {
+		return a.foo.Spec.DesiredReplicas, err
+	}
+
+	namespaceBars, err := a.Loader.GetNamespaceBars(a.Context, target.client, a.foo.Namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	allowedReplicas := a.foo.Spec.DesiredReplicas
+	for _, quota := range quotas {
+		if quota.ExceedsMaxDesiredReplicas(allowedReplicas) {
+			allowedReplicas = *quota.Spec.MaxDesiredReplicas
+		}
+
+		if quota.Spec.MaxBars != nil {
+			maxReplicas := currentReplicas + *quota.Spec.MaxBars - len(namespaceBars)
+			if maxReplicas < allowedReplicas {
+				allowedReplicas = maxReplicas
+			}
+		}
+	}
+
+	if allowedReplicas < currentReplicas {
+		return currentReplicas, nil
+	}
+
+	return allowedReplicas, nil
+}
+
+// getReplicaStatuses returns the status of the given Bar replicas sorted by name. Only the first ones are returned
+// when there are more than the maximum number of replica statuses, along with the number of replicas left out.
+func (a *adapter) getReplicaStatuses(replicas []v1alpha1.Bar, template *v1alpha1.Bar) ([]v1alpha1.ReplicaStatus, int) {
+	replicaStatuses := make([]v1alpha1.ReplicaStatus, 0, len(replicas))
+	for i := range replicas {
+		replica := &replicas[i]
+		replicaStatuses = append(replicaStatuses, v1alpha1.ReplicaStatus{
+			Name:               replica.Name,
+			UID:                replica.UID,
+			CreationTimestamp:  replica.CreationTimestamp,
+			TemplateRevision:   hashBarTemplate(replica, template),
+			Ready:              replica.IsReady(),
+			LastTransitionTime: replica.GetReadyTransitionTime(),
+		})
+	}
+
+	sort.Slice(replicaStatuses, func(i, j int) bool {
+		return replicaStatuses[i].Name < replicaStatuses[j].Name
+	})
+
+	if a.maxReplicaStatuses > 0 && len(replicaStatuses) > a.maxReplicaStatuses {
+		return replicaStatuses[:a.maxReplicaStatuses], len(replicaStatuses) - a.maxReplicaStatuses
+	}
+
+	return replicaStatuses, 0
+}
+
+// getPlacementTargets returns the clusters the Bar resources of this resource are placed into. The cluster the Foo
+// resource lives in is the only target when no placement is set.
+func (a *adapter) getPlacementTargets() ([]placementTarget, error) {
+	if a.foo.Spec.Placement == nil {
+		return []placementTarget{{client: a.Client}}, nil
+	}
+
+	targets := make([]placementTarget, 0, len(a.foo.Spec.Placement.Clusters))
+	for _, name := range a.foo.Spec.Placement.Clusters {
+		if a.hub == nil {
+			return nil, fmt.Errorf("member cluster %q can't be used as multi-cluster mode is disabled", name)
+		}
+
+		member, found := a.hub.GetMember(name)
+		if !found {
+			return nil, fmt.Errorf("unknown member cluster %q", name)
+		}
+
+		targets = append(targets, newMemberTarget(name, member))
+	}
+
+	return targets, nil
+}
+
+// getRemovedTargets returns the clusters holding Bar resources of this resource which are not part of its placement
+// anymore: the cluster the Foo resource lives in when a placement is set, and the recorded member clusters no longer
+// listed in the placement.
+func (a *adapter) getRemovedTargets() []placementTarget {
+	var targets []placementTarget
+	if a.foo.Spec.Placement != nil {
+		targets = append(targets, placementTarget{client: a.Client})
+	}
+
+	for _, target := range a.getMemberTargets(a.foo.Status.PlacedClusters) {
+		if a.foo.Spec.Placement == nil || !containsString(a.foo.Spec.Placement.Clusters, target.name) {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// getAllTargets returns every cluster which might hold Bar resources of this resource: the cluster the Foo resource
+// lives in, the member clusters in its placement and the recorded member clusters.
+func (a *adapter) getAllTargets() []placementTarget {
+	var names []string
+	if a.foo.Spec.Placement != nil {
+		names = append(names, a.foo.Spec.Placement.Clusters...)
+	}
+	for _, name := range a.foo.Status.PlacedClusters {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return append([]placementTarget{{client: a.Client}}, a.getMemberTargets(names)...)
 }
 
-// finalizeResource deletes all the Bar resources associated with this resource.
-func (a *adapter) finalizeResource() error {
-	bars, err := a.loader.GetBars(a.ctx, a.client, a.foo)
+// getMemberTargets returns the member clusters with the given names. Member clusters which are not loaded are left
+// out, as their Bar resources can't be reached.
+func (a *adapter) getMemberTargets(names []string) []placementTarget {
+	var targets []placementTarget
+	for _, name := range names {
+		var member cluster.Cluster
+		found := false
+		if a.hub != nil {
+			member, found = a.hub.GetMember(name)
+		}
+		if !found {
+			a.Logger.Info("Skipping unknown member cluster", "Cluster", name)
+			continue
+		}
+
+		targets = append(targets, newMemberTarget(name, member))
+	}
+
+	return targets
+}
+
+// recordPlacedClusters records the member clusters among the given targets in the status of the Foo resource.
+func (a *adapter) recordPlacedClusters(targets []placementTarget) error {
+	recorded := a.foo.DeepCopy()
+	for _, target := range targets {
+		if !target.isLocal() {
+			recorded.AddPlacedCluster(target.name)
+		}
+	}
+	if len(recorded.Status.PlacedClusters) == len(a.foo.Status.PlacedClusters) {
+		return nil
+	}
+
+	return a.PatchStatus(func() {
+		for _, target := range targets {
+			if !target.isLocal() {
+				a.foo.AddPlacedCluster(target.name)
+			}
+		}
+	})
+}
+
+// getFinalizerDomains returns the configured finalizer domain, along with the default one when a different domain is
+// configured, as Foo resources added before still contain finalizers within it.
+func (a *adapter) getFinalizerDomains() []string {
+	defaultDomain := operatorconfig.DefaultConfig().FinalizerDomain
+	if a.finalizerDomain == defaultDomain {
+		return []string{a.finalizerDomain}
+	}
+
+	return []string{a.finalizerDomain, defaultDomain}
+}
+
+// getUnknownFinalizers returns the finalizers of the Foo resource recorded as added for a finalizer handler within the
+// finalizer domains whose handler isn't registered anymore. The finalizers the operator didn't add are never returned,
+// whatever their domain.
+func (a *adapter) getUnknownFinalizers() []string {
+	domains := a.getFinalizerDomains()
+	handlerNames := a.finalizers.GetNames()
+	recorded := a.foo.GetHandlerFinalizers()
+
+	var unknownFinalizers []string
+	for _, finalizer := range a.foo.Finalizers {
+		if finalizer == a.finalizerName || finalizer == defaultFinalizerName || !containsString(recorded, finalizer) {
+			continue
+		}
+
+		domain, name, found := strings.Cut(finalizer, "/")
+		if found && containsString(domains, domain) && !containsString(handlerNames, name) {
+			unknownFinalizers = append(unknownFinalizers, finalizer)
+		}
+	}
+
+	return unknownFinalizers
+}
+
+// getHandlerFinalizerNames returns the names of the finalizers of the registered finalizer handlers.
+func (a *adapter) getHandlerFinalizerNames() []string {
+	var finalizerNames []string
+	for _, name := range a.finalizers.GetNames() {
+		finalizerNames = append(finalizerNames, finalizers.GetFinalizerName(a.finalizerDomain, name))
+	}
+
+	return finalizerNames
+}
+
+// finalizeBars deletes the Bar resources of the Foo resource and returns true once they are all gone. The Foo resource
+// is marked as terminating until then, unless the finalization timeout is reached, in which case a warning event is
+// recorded and true is returned as well.
+func (a *adapter) finalizeBars() (bool, time.Duration, error) {
+	remainingBars, requeueDelay, err := a.finalizeResource()
+	if err != nil || len(remainingBars) == 0 {
+		return err == nil, 0, err
+	}
+
+	terminatingFor := time.Since(a.foo.GetDeletionTimestamp().Time)
+	if a.finalizationTimeout > 0 && terminatingFor >= a.finalizationTimeout {
+		a.Logger.Info("Finalization timed out, removing the finalizer", "RemainingBars", remainingBars)
+		a.recorder.Eventf(a.foo, corev1.EventTypeWarning, FinalizationTimedOutEventReason,
+			"Finalizer removed after waiting %s for %d Bar resources to be deleted: %s",
+			a.finalizationTimeout, len(remainingBars), strings.Join(remainingBars, ", "))
+
+		return true, 0, nil
+	}
+
+	err = a.PatchStatus(func() {
+		a.foo.MarkTerminating(remainingBars)
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	if a.finalizationTimeout > 0 {
+		requeueDelay = minRequeueDelay(requeueDelay, a.finalizationTimeout-terminatingFor)
+	}
+
+	// Otherwise the Foo resource is reconciled again when its remaining Bar resources are deleted
+	return false, requeueDelay, nil
+}
+
+// newHandlerFinalize returns a function running the finalizer handler with the given name. The failure of the handler
+// is recorded in the Foo resource status and returned, so it is retried when the Foo resource is requeued, until the
+// finalization timeout is reached. A warning event is recorded then and the handler is given up on.
+func (a *adapter) newHandlerFinalize(name string) func() (bool, time.Duration, error) {
+	return func() (bool, time.Duration, error) {
+		err := a.finalizers.Run(a.Context, name, a.Client, a.foo)
+		if err != nil {
+			a.Logger.Error(err, "Finalizer handler failed", "Handler", name)
+
+			terminatingFor := time.Since(a.foo.GetDeletionTimestamp().Time)
+			if a.finalizationTimeout > 0 && terminatingFor >= a.finalizationTimeout {
+				a.Logger.Info("Finalization timed out, removing the finalizer", "Handler", name)
+				a.recorder.Eventf(a.foo, corev1.EventTypeWarning, FinalizationTimedOutEventReason,
+					"Finalizer handler %s given up on after failing for %s: %v", name, a.finalizationTimeout, err)
+
+				return true, 0, nil
+			}
+
+			patchErr := a.PatchStatus(func() {
+				a.foo.MarkFinalizerFailed(name, err)
+			})
+			if patchErr != nil {
+				return false, 0, patchErr
+			}
+
+			return false, 0, fmt.Errorf("finalizer handler %s failed: %w", name, err)
+		}
+
+		if a.foo.DeepCopy().RemoveFinalizerStatus(name) {
+			err = a.PatchStatus(func() {
+				a.foo.RemoveFinalizerStatus(name)
+			})
+			if err != nil {
+				return false, 0, err
+			}
+		}
+		a.Logger.Info("Finalizer handler succeeded", "Handler", name)
+
+		return true, 0, nil
+	}
+}
+
+// newUnknownFinalize returns a function removing the given finalizer, whose handler isn't registered, with a warning
+// event, as nothing would remove it otherwise.
+func (a *adapter) newUnknownFinalize(finalizer string) func() (bool, time.Duration, error) {
+	return func() (bool, time.Duration, error) {
+		a.Logger.Info("Removing finalizer without a registered handler", "Finalizer", finalizer)
+		a.recorder.Eventf(a.foo, corev1.EventTypeWarning, UnknownFinalizerRemovedEventReason,
+			"Finalizer %s removed, as no finalizer handler is registered for it", finalizer)
+
+		return true, 0, nil
+	}
+}
+
+// drainBar asks the given Bar resource to drain and deletes it. Only the Bar resources in member clusters are asked to
+// drain here, the Bar controller asks the other ones once they are deleted.
+func (a *adapter) drainBar(target placementTarget, bar *v1alpha1.Bar) error {
+	err := a.requestDrain(target, bar)
 	if err != nil {
 		return err
 	}
 
-	for _, bar := range bars {
-		err = a.client.Delete(a.ctx, &bar)
+	if bar.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	return target.client.Delete(a.Context, bar)
+}
+
+// deleteAllBars deletes the given Bar resources, which must be all the Bar resources of the Foo resource in the target.
+// The ones carrying the labels of the Foo replicas along with the UID of the Foo resource are deleted with a single
+// DeleteAllOf call, so the number of calls doesn't grow with the number of Bar resources unless they are in a member
+// cluster and have to be asked to drain first. As the UID label is what makes them part of the Foo resource, no Bar
+// resource of another Foo resource can match. The ones whose labels drifted, or which are only owned through their
+// controller reference, are deleted one by one.
+func (a *adapter) deleteAllBars(target placementTarget, bars []v1alpha1.Bar) error {
+	replicaLabels := a.newBarTemplate().Labels
+	replicaLabels[v1alpha1.FooUIDLabel] = string(a.foo.UID)
+	selector := labels.SelectorFromSet(replicaLabels)
+
+	var labeledBars int
+	for i := range bars {
+		bar := &bars[i]
+		if !selector.Matches(labels.Set(bar.Labels)) {
+			err := a.drainBar(target, bar)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+
+		err := a.requestDrain(target, bar)
 		if err != nil && !errors.IsNotFound(err) {
 			return err
 		}
+		if bar.GetDeletionTimestamp() == nil {
+			labeledBars++
+		}
+	}
+
+	if labeledBars == 0 {
+		return nil
+	}
+
+	a.Logger.Info("Deleting Bars", "Count", labeledBars, "Cluster", target.name)
+
+	return target.client.DeleteAllOf(a.Context, &v1alpha1.Bar{}, client.InNamespace(a.foo.Namespace),
+		client.MatchingLabelsSelector{Selector: selector})
+}
 
-		a.logger.Info("Deleted bar", "bar.Name", bar.Name, "bar.Namespace", bar.Namespace)
+// requestDrain asks the given Bar resource in a member cluster to drain if it wasn't asked yet. The drain finalizer is
+// added to the ones created before it existed, so they are kept until drained once deleted. Nothing is done for the
+// Bar resources in the cluster the Foo resource lives in, as the Bar controller drains them, nor when the Foo resource
+// gives its Bar resources no time to drain.
+func (a *adapter) requestDrain(target placementTarget, bar *v1alpha1.Bar) error {
+	if target.isLocal() || a.foo.GetTerminationGracePeriod() == 0 {
+		return nil
 	}
 
-	a.logger.Info("Successfully finalized Foo")
+	if !a.hasDrainFinalizer(bar) {
+		patch := client.MergeFrom(bar.DeepCopy())
+		controllerutil.AddFinalizer(bar, a.drainFinalizerName)
+		err := target.client.Patch(a.Context, bar, patch)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !bar.IsDraining() {
+		patch := client.MergeFrom(bar.DeepCopy())
+		bar.MarkDraining(time.Now().Add(a.foo.GetTerminationGracePeriod()))
+		err := target.client.Status().Patch(a.Context, bar, patch)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+// hasDrainFinalizer returns true if the given Bar resource contains the configured or the default drain finalizer.
+func (a *adapter) hasDrainFinalizer(bar *v1alpha1.Bar) bool {
+	return controllerutil.ContainsFinalizer(bar, a.drainFinalizerName) ||
+		controllerutil.ContainsFinalizer(bar, defaultDrainFinalizerName)
+}
+
+// releaseDrainedBars removes the drain finalizers from the given Bar resources being deleted in a member cluster that
+// are drained or reached their drain deadline. Bar resources deleted without being asked to drain first are asked to
+// do it now, unless the Foo resource gives them no time to drain. The time left until the next drain deadline is
+// returned, or zero if no Bar resource is draining anymore.
+func (a *adapter) releaseDrainedBars(target placementTarget, bars []v1alpha1.Bar) (time.Duration, error) {
+	var requeueDelay time.Duration
+	for i := range bars {
+		bar := &bars[i]
+		if bar.GetDeletionTimestamp() == nil || !a.hasDrainFinalizer(bar) {
+			continue
+		}
+
+		if !bar.IsDraining() && a.foo.GetTerminationGracePeriod() > 0 {
+			err := a.drainBar(target, bar)
+			if err != nil && !errors.IsNotFound(err) {
+				return 0, err
+			}
+		}
+
+		if timeLeft := bar.GetDrainTimeLeft(time.Now()); timeLeft > 0 {
+			requeueDelay = minRequeueDelay(requeueDelay, timeLeft)
+			continue
+		}
+
+		patch := client.MergeFrom(bar.DeepCopy())
+		controllerutil.RemoveFinalizer(bar, a.drainFinalizerName)
+		controllerutil.RemoveFinalizer(bar, defaultDrainFinalizerName)
+		err := target.client.Patch(a.Context, bar, patch)
+		if err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+		loader.Invalidate(a.Loader)
+
+		a.Logger.Info("Bar deleted", "Bar.Name", bar.Name, "Bar.Namespace", bar.Namespace, "Cluster", target.name,
+			"Drained", bar.IsDrained())
+	}
+
+	return requeueDelay, nil
+}
+
+// finalizeResource drains and deletes all the Bar resources associated with this resource, in every cluster they might
+// have been placed into. The names of the Bar resources which still exist are returned, prefixed with their member
+// cluster if any, along with the time left until the next drain deadline, or zero if no Bar resource is draining
+// anymore.
+func (a *adapter) finalizeResource() ([]string, time.Duration, error) {
+	remainingBars := []string{}
+	var requeueDelay time.Duration
+	for _, target := range a.getAllTargets() {
+		bars, err := a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		err = a.deleteAllBars(target, bars)
+		if err != nil {
+			return nil, 0, err
+		}
+		loader.Invalidate(a.Loader)
+
+		// The Bar resources in member clusters are loaded again, so the ones deleted above are seen as being deleted.
+		// The Bar controller releases the ones in the cluster the Foo resource lives in.
+		if !target.isLocal() {
+			bars, err = a.Loader.GetBars(a.Context, target.client, a.foo)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			targetRequeueDelay, err := a.releaseDrainedBars(target, bars)
+			if err != nil {
+				return nil, 0, err
+			}
+			requeueDelay = minRequeueDelay(requeueDelay, targetRequeueDelay)
+		}
+
+		// Bar resources might still exist after being released, e.g. if they have other finalizers
+		bars, err = a.Loader.GetBars(a.Context, target.client, a.foo)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, bar := range bars {
+			remainingBars = append(remainingBars, target.qualifiedName(bar.Name))
+		}
+	}
+
+	if len(remainingBars) == 0 {
+		a.Logger.Info("Successfully finalized Foo")
+	}
+
+	return remainingBars, requeueDelay, nil
+}
+
+// newBarTemplate returns the labels and spec every Bar replica of the Foo resource is created with. Bars created in
+// the cluster the Foo resource lives in get the same labels from the Bar webhook, but member clusters don't run it.
+func (a *adapter) newBarTemplate() *v1alpha1.Bar {
+	return &v1alpha1.Bar{
+		ObjectMeta: v1.ObjectMeta{
+			Labels: map[string]string{
+				v1alpha1.FooLabel:       a.foo.Name,
+				v1alpha1.ManagedByLabel: v1alpha1.ManagedByLabelValue,
+			},
+		},
+		Spec: v1alpha1.BarSpec{
+			Foo: a.foo.Name,
+		},
+	}
+}
+
+// getActiveBars returns the given Bar resources which are not being deleted.
+func getActiveBars(bars []v1alpha1.Bar) []v1alpha1.Bar {
+	active := make([]v1alpha1.Bar, 0, len(bars))
+	for _, bar := range bars {
+		if bar.GetDeletionTimestamp() == nil {
+			active = append(active, bar)
+		}
+	}
+
+	return active
+}
+
+// hashBarTemplate returns a hash of the fields of the given Bar resource managed by its Foo resource, which are the
+// Foo reference, the labels set in the given template and the workload when the template sets one, so it can be
+// compared with the hash of the template to detect drifts. The workload of the Bar resource is left to its users
+// otherwise.
+func hashBarTemplate(bar, template *v1alpha1.Bar) string {
+	labels := map[string]string{}
+	for key := range template.Labels {
+		if value, found := bar.Labels[key]; found {
+			labels[key] = value
+		}
+	}
+
+	var workload *v1alpha1.BarWorkload
+	if template.Spec.Workload != nil {
+		workload = bar.Spec.Workload
+	}
+
+	data, _ := json.Marshal(struct {
+		Foo      string
+		Labels   map[string]string
+		Workload *v1alpha1.BarWorkload `json:",omitempty"`
+	}{bar.Spec.Foo, labels, workload})
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// containsString returns true if the given list contains the given value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minRequeueDelay returns the smallest of the given delays, ignoring zero as it means no requeue is needed.
+func minRequeueDelay(delay, other time.Duration) time.Duration {
+	if delay == 0 || (other > 0 && other < delay) {
+		return other
+	}
+
+	return delay
+}
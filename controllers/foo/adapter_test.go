@@ -0,0 +1,775 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package foo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	barcontroller "github.com/konflux-ci/operator-toolkit-example/controllers/bar"
+	"github.com/konflux-ci/operator-toolkit-example/finalizers"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/multicluster"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Foo adapter", func() {
+	const namespace = "default"
+
+	var (
+		foo *v1alpha1.Foo
+		h   *harness.Harness
+	)
+
+	newAdapter := func(objectLoader loader.ObjectLoader) *adapter {
+		h.Reload(foo)
+		return NewAdapter(h.Context, h.Client, foo, nil, objectLoader, h.Recorder, h.Logger)
+	}
+
+	operations := func(a *adapter) []controller.Operation {
+		return []controller.Operation{
+			a.EnsureFinalizersAreCalled,
+			a.EnsureFinalizerIsAdded,
+			a.EnsureMaximumReplicas,
+			a.EnsureUnplacedReplicasAreDeleted,
+			a.EnsureMinimumReplicas,
+			a.EnsureDriftIsHandled,
+			a.EnsureReplicaDataConsistency,
+			a.EnsureReconcileRequestIsHandled,
+			a.EnsureDrainedReplicasAreDeleted,
+			a.EnsureResyncIsScheduled,
+		}
+	}
+
+	getActiveBarsOf := func(result *harness.Result) []v1alpha1.Bar {
+		return getActiveBars(result.GetBarsOf(foo))
+	}
+
+	// releaseBars runs the drain operation of the Bar controller on the Bars being deleted, as it is the one releasing
+	// them
+	releaseBars := func() {
+		for _, bar := range h.Run(namespace).Bars {
+			if bar.DeletionTimestamp == nil {
+				continue
+			}
+			a := barcontroller.NewAdapter(h.Context, h.Client, &bar, loader.NewLoader(), h.Recorder, h.Logger)
+			Expect(h.Run(namespace, a.EnsureDrainIsFinished).Err).NotTo(HaveOccurred())
+		}
+	}
+
+	BeforeEach(func() {
+		foo = harness.NewFoo("foo", namespace).WithDesiredReplicas(2).Build()
+	})
+
+	It("adds the finalizer", func() {
+		h = harness.New(foo)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureFinalizerIsAdded)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetFoo(foo.Name).Finalizers).To(ContainElement(defaultFinalizerName))
+	})
+
+	It("adds the configured finalizer", func() {
+		h = harness.New(foo)
+		a := newAdapter(loader.NewLoader())
+		a.finalizerName = "example.com/finalizer"
+
+		result := h.Run(namespace, a.EnsureFinalizerIsAdded)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetFoo(foo.Name).Finalizers).To(ConsistOf("example.com/finalizer"))
+	})
+
+	It("replaces the default finalizer with the configured one", func() {
+		foo.Finalizers = []string{defaultFinalizerName}
+		h = harness.New(foo)
+		a := newAdapter(loader.NewLoader())
+		a.finalizerName = "example.com/finalizer"
+
+		result := h.Run(namespace, a.EnsureFinalizerIsAdded)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetFoo(foo.Name).Finalizers).To(ConsistOf("example.com/finalizer"))
+	})
+
+	It("removes the default finalizer of the Foos being deleted when another one is configured", func() {
+		foo.Spec.DesiredReplicas = 0
+		foo.Finalizers = []string{defaultFinalizerName}
+		h = harness.New(foo)
+		Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+		a := newAdapter(loader.NewLoader())
+		a.finalizerName = "example.com/finalizer"
+
+		result := h.Run(namespace, operations(a)...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.Foos).To(BeEmpty())
+	})
+
+	It("creates the missing replicas and reports them in the status", func() {
+		h = harness.New(foo)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, operations(a)...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBarsOf(foo)).To(HaveLen(2))
+
+		reconciledFoo := result.GetFoo(foo.Name)
+		Expect(reconciledFoo.Status.Replicas).To(HaveLen(2))
+		Expect(apimeta.FindStatusCondition(reconciledFoo.Status.Conditions, "Health").Reason).To(Equal(v1alpha1.HealthyReason.String()))
+	})
+
+	It("reports the details of the replicas sorted by name", func() {
+		workload := &v1alpha1.BarWorkload{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "busybox"}},
+		}}}
+		ready := harness.NewBar("bar-b", namespace).ForFoo(foo).WithWorkload(workload).Build()
+		ready.MarkWorkloadReady()
+		h = harness.New(foo,
+			harness.NewBar("bar-c", namespace).ForFoo(foo).WithWorkload(workload).Build(),
+			ready,
+			harness.NewBar("bar-a", namespace).ForFoo(foo).WithLabels(map[string]string{
+				v1alpha1.ManagedByLabel: "someone-else",
+			}).Build(),
+		)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureReplicaDataConsistency)
+		Expect(result.Err).NotTo(HaveOccurred())
+
+		status := result.GetFoo(foo.Name).Status
+		Expect(status.TemplateRevision).NotTo(BeEmpty())
+		Expect(status.OmittedReplicas).To(BeZero())
+		Expect(status.ReplicaDetails).To(HaveLen(3))
+		for i, name := range []string{"bar-a", "bar-b", "bar-c"} {
+			bar := result.GetBar(name)
+			Expect(status.ReplicaDetails[i].Name).To(Equal(name))
+			Expect(status.ReplicaDetails[i].UID).To(Equal(bar.UID))
+			Expect(status.ReplicaDetails[i].CreationTimestamp.Unix()).To(Equal(bar.CreationTimestamp.Unix()))
+		}
+
+		Expect(status.ReplicaDetails[0].TemplateRevision).NotTo(Equal(status.TemplateRevision))
+		Expect(status.ReplicaDetails[0].Ready).To(BeTrue())
+		Expect(status.ReplicaDetails[1].TemplateRevision).To(Equal(status.TemplateRevision))
+		Expect(status.ReplicaDetails[1].Ready).To(BeTrue())
+		Expect(status.ReplicaDetails[1].LastTransitionTime.Unix()).To(Equal(
+			apimeta.FindStatusCondition(ready.Status.Conditions, "WorkloadReady").LastTransitionTime.Unix()))
+		Expect(status.ReplicaDetails[2].Ready).To(BeFalse())
+	})
+
+	It("only lists the configured number of replicas", func() {
+		h = harness.New(foo,
+			harness.NewBar("bar-3", namespace).ForFoo(foo).Build(),
+			harness.NewBar("bar-1", namespace).ForFoo(foo).Build(),
+			harness.NewBar("bar-2", namespace).ForFoo(foo).Build(),
+		)
+		a := newAdapter(loader.NewLoader())
+		a.maxReplicaStatuses = 2
+
+		result := h.Run(namespace, a.EnsureReplicaDataConsistency)
+		Expect(result.Err).NotTo(HaveOccurred())
+
+		status := result.GetFoo(foo.Name).Status
+		Expect(status.Replicas).To(Equal([]string{"bar-1", "bar-2"}))
+		Expect(status.ReplicaDetails).To(HaveLen(2))
+		Expect(status.ReplicaDetails[0].Name).To(Equal("bar-1"))
+		Expect(status.ReplicaDetails[1].Name).To(Equal("bar-2"))
+		Expect(status.OmittedReplicas).To(Equal(1))
+
+		Expect(h.Client.Delete(h.Context, result.GetBar("bar-3"))).To(Succeed())
+		result = h.Run(namespace, newAdapter(loader.NewLoader()).EnsureReplicaDataConsistency)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetFoo(foo.Name).Status.ReplicaDetails).To(HaveLen(2))
+		Expect(result.GetFoo(foo.Name).Status.OmittedReplicas).To(BeZero())
+	})
+
+	It("drains the extra replicas and deletes them once they acknowledge it", func() {
+		h = harness.New(foo,
+			harness.NewBar("bar-1", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+			harness.NewBar("bar-2", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+			harness.NewBar("bar-3", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+		)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, operations(a)...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBarsOf(foo)).To(HaveLen(3))
+		Expect(getActiveBarsOf(result)).To(HaveLen(2))
+		Expect(result.GetFoo(foo.Name).Status.Replicas).To(ConsistOf("bar-1", "bar-2"))
+
+		releaseBars()
+		drained := h.Run(namespace).GetBar("bar-3")
+		Expect(drained.IsDraining()).To(BeTrue())
+		drained.Annotations = map[string]string{v1alpha1.DrainedAnnotation: "true"}
+		Expect(h.Client.Update(h.Context, drained)).To(Succeed())
+		releaseBars()
+
+		result = h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.Result.RequeueAfter).To(BeZero())
+		Expect(result.GetBarsOf(foo)).To(HaveLen(2))
+	})
+
+	It("deletes the draining replicas once their drain deadline is reached", func() {
+		var gracePeriod int64 = 5
+		foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		h = harness.New(foo,
+			harness.NewBar("bar-1", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+			harness.NewBar("bar-2", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+			harness.NewBar("bar-3", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+		)
+
+		result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+
+		releaseBars()
+		expired := h.Run(namespace).GetBar("bar-3")
+		Expect(expired.GetDrainTimeLeft(time.Now())).To(BeNumerically("~", 5*time.Second, time.Second))
+		expired.MarkDraining(time.Now().Add(-time.Second))
+		Expect(h.Client.Status().Update(h.Context, expired)).To(Succeed())
+		releaseBars()
+
+		result = h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBar("bar-3")).To(BeNil())
+	})
+
+	It("creates the replicas with the drain finalizer", func() {
+		h = harness.New(foo)
+
+		result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		for _, bar := range result.GetBarsOf(foo) {
+			Expect(bar.Finalizers).To(ContainElement(defaultDrainFinalizerName))
+		}
+	})
+
+	It("stores the template hash on the replicas it creates", func() {
+		h = harness.New(foo)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureMinimumReplicas, a.EnsureReplicaDataConsistency, a.EnsureDriftIsHandled)
+		Expect(result.Err).NotTo(HaveOccurred())
+		for _, bar := range result.GetBarsOf(foo) {
+			Expect(bar.Annotations).To(HaveKeyWithValue(v1alpha1.TemplateHashAnnotation,
+				result.GetFoo(foo.Name).Status.TemplateRevision))
+		}
+
+		condition := apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "DriftDetected")
+		Expect(condition.Reason).To(Equal(v1alpha1.NoDriftReason.String()))
+	})
+
+	It("creates the replicas without the drain finalizer when they are given no time to drain", func() {
+		var gracePeriod int64
+		foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		h = harness.New(foo)
+
+		result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBarsOf(foo)).To(HaveLen(2))
+		for _, bar := range result.GetBarsOf(foo) {
+			Expect(bar.Finalizers).To(BeEmpty())
+		}
+	})
+
+	It("limits the replicas created to the ones allowed by the FooQuotas", func() {
+		maxBars := 1
+		h = harness.New(foo, &v1alpha1.FooQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: namespace},
+			Spec:       v1alpha1.FooQuotaSpec{MaxBars: &maxBars},
+		})
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, operations(a)...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBarsOf(foo)).To(HaveLen(1))
+		Expect(apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "Health").Reason).To(Equal(v1alpha1.QuotaExceededReason.String()))
+	})
+
+	It("ignores the FooQuotas when they are not enforced", func() {
+		maxBars := 1
+		h = harness.New(foo, &v1alpha1.FooQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: namespace},
+			Spec:       v1alpha1.FooQuotaSpec{MaxBars: &maxBars},
+		})
+		a := newAdapter(loader.NewLoader())
+		a.enforceQuotas = false
+
+		result := h.Run(namespace, operations(a)...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetBarsOf(foo)).To(HaveLen(2))
+	})
+
+	It("requeues with an error when listing the replicas fails", func() {
+		h = harness.New(foo)
+		mockLoader := loader.NewMockLoader().
+			OnGetBars().Return([]v1alpha1.Bar{}, nil).
+			OnGetBars().Return(nil, fmt.Errorf("list error")).
+			WithFallThrough()
+		a := newAdapter(mockLoader)
+
+		result := h.Run(namespace, a.EnsureMaximumReplicas, a.EnsureMinimumReplicas)
+		Expect(result.Err).To(MatchError("list error"))
+		Expect(result.Bars).To(BeEmpty())
+		Expect(mockLoader.AssertExpectationsMet()).To(Succeed())
+	})
+
+	It("drains and deletes the replicas before releasing the Foo being deleted", func() {
+		foo = harness.NewFoo("foo", namespace).WithDesiredReplicas(2).WithFinalizers(defaultFinalizerName).Build()
+		h = harness.New(foo,
+			harness.NewBar("bar-1", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+			harness.NewBar("bar-2", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+		)
+		Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+
+		result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.Result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(result.Foos).To(HaveLen(1))
+		Expect(getActiveBarsOf(result)).To(BeEmpty())
+
+		releaseBars()
+		result = h.Run(namespace)
+		for i := range result.Bars {
+			Expect(result.Bars[i].IsDraining()).To(BeTrue())
+			result.Bars[i].Annotations = map[string]string{v1alpha1.DrainedAnnotation: "true"}
+			Expect(h.Client.Update(h.Context, &result.Bars[i])).To(Succeed())
+		}
+		releaseBars()
+
+		result = h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.Bars).To(BeEmpty())
+		Expect(result.Foos).To(BeEmpty())
+	})
+
+	Context("when the replicas carry the labels of the Foo", func() {
+		var counter *harness.CountingClient
+
+		newCountingAdapter := func() *adapter {
+			h.Reload(foo)
+			counter = harness.NewCountingClient(h.Client)
+			return NewAdapter(h.Context, counter, foo, nil, loader.NewLoader(), h.Recorder, h.Logger)
+		}
+
+		It("deletes them with a single request when scaling to zero", func() {
+			foo.Spec.DesiredReplicas = 0
+			h = harness.New(foo,
+				harness.NewBar("bar-1", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+				harness.NewBar("bar-2", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+				harness.NewBar("bar-3", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+			)
+
+			result := h.Run(namespace, newCountingAdapter().EnsureMaximumReplicas)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBarsOf(foo)).To(HaveLen(3))
+			Expect(getActiveBarsOf(result)).To(BeEmpty())
+			Expect(counter.Count("deletecollection")).To(Equal(1))
+			Expect(counter.Count("delete")).To(BeZero())
+			Expect(counter.Count("patch")).To(BeZero())
+			Expect(counter.Count("status/patch")).To(BeZero())
+		})
+
+		It("deletes the drifted ones one by one before releasing the Foo being deleted", func() {
+			foo.Finalizers = []string{defaultFinalizerName}
+			drifted := harness.NewBar("bar-3", namespace).ForFoo(foo).WithLabels(map[string]string{
+				v1alpha1.ManagedByLabel: "someone-else",
+			}).WithFinalizers(defaultDrainFinalizerName).Build()
+			h = harness.New(foo,
+				harness.NewBar("bar-1", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+				harness.NewBar("bar-2", namespace).ForFoo(foo).WithFinalizers(defaultDrainFinalizerName).Build(),
+				drifted,
+			)
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+
+			result := h.Run(namespace, newCountingAdapter().EnsureFinalizersAreCalled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Foos).To(HaveLen(1))
+			Expect(getActiveBarsOf(result)).To(BeEmpty())
+			Expect(counter.Count("deletecollection")).To(Equal(1))
+			Expect(counter.Count("delete")).To(Equal(1))
+		})
+	})
+
+	Context("when a Bar matches another Foo", func() {
+		var other *v1alpha1.Foo
+
+		newOtherAdapter := func() *adapter {
+			h.Reload(other)
+			return NewAdapter(h.Context, h.Client, other, nil, loader.NewLoader(), h.Recorder, h.Logger)
+		}
+
+		// The Bar is owned by foo, while its label and spec point to other
+		BeforeEach(func() {
+			foo.Spec.DesiredReplicas = 1
+			other = harness.NewFoo("other", namespace).WithFinalizers(defaultFinalizerName).Build()
+			shared := harness.NewBar("bar-1", namespace).ForFoo(foo).WithOwner(foo).
+				WithLabels(map[string]string{v1alpha1.FooLabel: other.Name}).Build()
+			shared.Spec.Foo = other.Name
+			h = harness.New(foo, other, shared)
+		})
+
+		It("leaves it to its owner when the other Foo scales to zero", func() {
+			result := h.Run(namespace, operations(newOtherAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(getActiveBarsOf(result)).To(HaveLen(1))
+			Expect(result.GetBarsOf(other)).To(BeEmpty())
+			Expect(result.GetFoo(other.Name).Status.Replicas).To(BeEmpty())
+		})
+
+		It("leaves it to its owner when the other Foo is deleted", func() {
+			Expect(h.Client.Delete(h.Context, other)).To(Succeed())
+
+			result := h.Run(namespace, operations(newOtherAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetFoo(other.Name)).To(BeNil())
+			Expect(getActiveBarsOf(result)).To(HaveLen(1))
+		})
+	})
+
+	Context("when a Bar drifted from its Foo", func() {
+		var drifted *v1alpha1.Bar
+
+		BeforeEach(func() {
+			drifted = harness.NewBar("bar-1", namespace).ForFoo(foo).Build()
+			drifted.Spec.Foo = "other"
+		})
+
+		It("reports the drifted Bar", func() {
+			h = harness.New(foo, drifted)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureMinimumReplicas, a.EnsureDriftIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(drifted.Name).Spec.Foo).To(Equal("other"))
+
+			condition := apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "DriftDetected")
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("1 Bar resources drifted: bar-1"))
+		})
+
+		It("corrects the drifted Bar with the Correct policy", func() {
+			foo.Spec.DriftPolicy = v1alpha1.DriftPolicyCorrect
+			h = harness.New(foo, drifted)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureMinimumReplicas, a.EnsureDriftIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBar(drifted.Name).Spec.Foo).To(Equal(foo.Name))
+			Expect(h.Recorder.Events).To(Receive(ContainSubstring(DriftCorrectedEventReason)))
+
+			condition := apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "DriftDetected")
+			Expect(condition.Reason).To(Equal(v1alpha1.DriftCorrectedReason.String()))
+
+			result = h.Run(namespace, newAdapter(loader.NewLoader()).EnsureDriftIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			condition = apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "DriftDetected")
+			Expect(condition.Reason).To(Equal(v1alpha1.NoDriftReason.String()))
+		})
+
+		It("corrects the replicas whose Foo label was edited, as they are still controlled by the Foo", func() {
+			foo.UID = "foo-uid"
+			foo.Spec.DriftPolicy = v1alpha1.DriftPolicyCorrect
+			drifted = harness.NewBar("bar-1", namespace).ForFoo(foo).WithOwner(foo).WithLabels(map[string]string{
+				v1alpha1.FooLabel: "edited",
+			}).Build()
+			h = harness.New(foo, drifted)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureMinimumReplicas, a.EnsureDriftIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetBarsOf(foo)).To(HaveLen(2))
+			Expect(result.GetBar(drifted.Name).Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, foo.Name))
+			template := a.newBarTemplate()
+			Expect(result.GetBar(drifted.Name).Annotations).To(HaveKeyWithValue(v1alpha1.TemplateHashAnnotation,
+				hashBarTemplate(template, template)))
+		})
+
+		It("reports the replicas whose template hash annotation doesn't match the template", func() {
+			drifted = harness.NewBar("bar-1", namespace).ForFoo(foo).Build()
+			drifted.Annotations = map[string]string{v1alpha1.TemplateHashAnnotation: "outdated"}
+			h = harness.New(foo, drifted)
+			a := newAdapter(loader.NewLoader())
+
+			result := h.Run(namespace, a.EnsureMinimumReplicas, a.EnsureDriftIsHandled)
+			Expect(result.Err).NotTo(HaveOccurred())
+
+			condition := apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "DriftDetected")
+			Expect(condition.Message).To(Equal("1 Bar resources drifted: bar-1"))
+		})
+	})
+
+	Context("when placed into member clusters", func() {
+		var (
+			hub    *multicluster.Hub
+			member *harness.Harness
+		)
+
+		newPlacedAdapter := func() *adapter {
+			h.Reload(foo)
+			return NewAdapter(h.Context, h.Client, foo, hub, loader.NewLoader(), h.Recorder, h.Logger)
+		}
+
+		getMemberBars := func() []v1alpha1.Bar {
+			bars := &v1alpha1.BarList{}
+			Expect(member.Client.List(h.Context, bars, client.InNamespace(namespace))).To(Succeed())
+			return bars.Items
+		}
+
+		BeforeEach(func() {
+			var gracePeriod int64
+			foo = harness.NewFoo("foo", namespace).WithDesiredReplicas(2).WithPlacement("member").Build()
+			foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+			member = harness.New()
+			hub = multicluster.NewHub(nil)
+			Expect(hub.AddMember("member", member.AsCluster())).To(Succeed())
+		})
+
+		It("records the member clusters and creates the replicas in them", func() {
+			h = harness.New(foo)
+
+			result := h.Run(namespace, operations(newPlacedAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Bars).To(BeEmpty())
+			Expect(getMemberBars()).To(HaveLen(2))
+			Expect(result.GetFoo(foo.Name).Status.PlacedClusters).To(Equal([]string{"member"}))
+		})
+
+		It("limits the replicas listed in the status of the member clusters", func() {
+			h = harness.New(foo)
+			Expect(h.Run(namespace, operations(newPlacedAdapter())...).Err).NotTo(HaveOccurred())
+			Expect(member.Client.Create(h.Context, harness.NewBar("bar-3", namespace).ForFoo(foo).Build())).To(
+				Succeed())
+			names := []string{}
+			for _, bar := range getMemberBars() {
+				names = append(names, bar.Name)
+			}
+			sort.Strings(names)
+
+			a := newPlacedAdapter()
+			a.maxReplicaStatuses = 2
+			result := h.Run(namespace, a.EnsureReplicaDataConsistency)
+			Expect(result.Err).NotTo(HaveOccurred())
+
+			clusters := result.GetFoo(foo.Name).Status.Clusters
+			Expect(clusters).To(HaveLen(1))
+			Expect(clusters[0].Replicas).To(Equal(names[:2]))
+			Expect(clusters[0].ReplicaDetails).To(HaveLen(2))
+			Expect(clusters[0].OmittedReplicas).To(Equal(1))
+		})
+
+		It("enforces the FooQuotas in the member clusters", func() {
+			maxBars := 1
+			quota := &v1alpha1.FooQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: namespace},
+				Spec:       v1alpha1.FooQuotaSpec{MaxBars: &maxBars},
+			}
+			h = harness.New(foo, quota)
+
+			result := h.Run(namespace, operations(newPlacedAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(getMemberBars()).To(HaveLen(1))
+			Expect(apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "Health").Reason).To(
+				Equal(v1alpha1.QuotaExceededReason.String()))
+		})
+
+		It("deletes the replicas in the clusters removed from the placement", func() {
+			h = harness.New(foo)
+			Expect(h.Run(namespace, operations(newPlacedAdapter())...).Err).NotTo(HaveOccurred())
+			Expect(getMemberBars()).To(HaveLen(2))
+
+			h.Reload(foo)
+			foo.Spec.Placement = nil
+			Expect(h.Client.Update(h.Context, foo)).To(Succeed())
+
+			result := h.Run(namespace, operations(newPlacedAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(getMemberBars()).To(BeEmpty())
+			Expect(result.GetBarsOf(foo)).To(HaveLen(2))
+
+			result = h.Run(namespace, operations(newPlacedAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetFoo(foo.Name).Status.PlacedClusters).To(BeEmpty())
+		})
+
+		It("deletes the replicas in the recorded clusters when the Foo is deleted", func() {
+			foo.Finalizers = []string{defaultFinalizerName}
+			foo.Spec.Placement.Clusters = []string{"unknown"}
+			foo.Status.PlacedClusters = []string{"member"}
+			h = harness.New(foo)
+			Expect(member.Client.Create(h.Context, harness.NewBar("bar", namespace).ForFoo(foo).Build())).To(Succeed())
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+
+			result := h.Run(namespace, operations(newPlacedAdapter())...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Foos).To(BeEmpty())
+			Expect(getMemberBars()).To(BeEmpty())
+		})
+	})
+
+	It("reports the reconcile requested with the annotation once handled", func() {
+		foo.Annotations = map[string]string{v1alpha1.ReconcileRequestedAtAnnotation: "2023-05-04T10:00:00Z"}
+		h = harness.New(foo)
+
+		result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.GetFoo(foo.Name).Status.LastHandledReconcileAt).To(Equal("2023-05-04T10:00:00Z"))
+	})
+
+	It("requeues the Foo after its resync period", func() {
+		foo.Spec.ResyncPeriod = &metav1.Duration{Duration: 5 * time.Minute}
+		h = harness.New(foo)
+
+		result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.Result.RequeueAfter).To(Equal(5 * time.Minute))
+	})
+
+	Context("when finalizer handlers are registered", func() {
+		var (
+			handlerErr error
+			registry   *finalizers.Registry
+		)
+
+		newAdapterWithRegistry := func() *adapter {
+			a := newAdapter(loader.NewLoader())
+			a.finalizers = registry
+			return a
+		}
+
+		BeforeEach(func() {
+			handlerErr = nil
+			registry = finalizers.NewRegistry()
+			Expect(registry.Register("external", func(context.Context, client.Client, *v1alpha1.Foo) error {
+				return handlerErr
+			})).To(Succeed())
+		})
+
+		It("adds a finalizer for every handler", func() {
+			h = harness.New(foo)
+
+			result := h.Run(namespace, newAdapterWithRegistry().EnsureFinalizerIsAdded)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetFoo(foo.Name).Finalizers).To(ConsistOf(defaultFinalizerName,
+				"appstudio.redhat.com/external"))
+			Expect(result.GetFoo(foo.Name).GetHandlerFinalizers()).To(ConsistOf("appstudio.redhat.com/external"))
+		})
+
+		It("keeps the Foo and reports the handler failures until the handler succeeds", func() {
+			foo.Finalizers = []string{defaultFinalizerName, "appstudio.redhat.com/external"}
+			h = harness.New(foo)
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+			handlerErr = fmt.Errorf("deregistration failed")
+
+			result := h.Run(namespace, newAdapterWithRegistry().EnsureFinalizersAreCalled)
+			Expect(result.Err).To(MatchError(ContainSubstring("deregistration failed")))
+			Expect(result.Foos).To(HaveLen(1))
+			Expect(result.GetFoo(foo.Name).Status.Finalizers).To(HaveLen(1))
+			Expect(result.GetFoo(foo.Name).Status.Finalizers[0].Name).To(Equal("external"))
+			Expect(result.GetFoo(foo.Name).Status.Finalizers[0].LastError).To(Equal("deregistration failed"))
+
+			handlerErr = nil
+			result = h.Run(namespace, newAdapterWithRegistry().EnsureFinalizersAreCalled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Foos).To(BeEmpty())
+		})
+
+		It("gives up on the failing handlers once the finalization timeout is reached", func() {
+			foo.Finalizers = []string{defaultFinalizerName, "appstudio.redhat.com/external"}
+			h = harness.New(foo)
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+			handlerErr = fmt.Errorf("deregistration failed")
+			a := newAdapterWithRegistry()
+			a.finalizationTimeout = time.Nanosecond
+
+			result := h.Run(namespace, a.EnsureFinalizersAreCalled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Foos).To(BeEmpty())
+			Expect(h.Recorder.Events).To(Receive(ContainSubstring(FinalizationTimedOutEventReason)))
+		})
+
+		It("removes the finalizers added for a handler which isn't registered anymore", func() {
+			foo.Finalizers = []string{defaultFinalizerName, "appstudio.redhat.com/removed", "example.com/other"}
+			foo.RecordHandlerFinalizers("appstudio.redhat.com/removed")
+			h = harness.New(foo)
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+
+			result := h.Run(namespace, newAdapterWithRegistry().EnsureFinalizersAreCalled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetFoo(foo.Name).Finalizers).To(ConsistOf("example.com/other"))
+			Expect(h.Recorder.Events).To(Receive(ContainSubstring(UnknownFinalizerRemovedEventReason)))
+		})
+
+		It("keeps the finalizers within the domain it didn't add", func() {
+			foo.Finalizers = []string{defaultFinalizerName, "appstudio.redhat.com/external",
+				"appstudio.redhat.com/someone-else"}
+			foo.RecordHandlerFinalizers("appstudio.redhat.com/external")
+			h = harness.New(foo)
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+
+			result := h.Run(namespace, newAdapterWithRegistry().EnsureFinalizersAreCalled)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.GetFoo(foo.Name).Finalizers).To(ConsistOf("appstudio.redhat.com/someone-else"))
+			Expect(h.Recorder.Events).NotTo(Receive(ContainSubstring(UnknownFinalizerRemovedEventReason)))
+		})
+	})
+
+	Context("when the Bars of the Foo being deleted have other finalizers", func() {
+		BeforeEach(func() {
+			foo = harness.NewFoo("foo", namespace).WithDesiredReplicas(1).WithFinalizers(defaultFinalizerName).Build()
+			h = harness.New(foo, harness.NewBar("bar-1", namespace).ForFoo(foo).Build())
+			Expect(h.Client.Delete(h.Context, foo)).To(Succeed())
+
+			bar := &v1alpha1.Bar{}
+			Expect(h.Client.Get(h.Context, client.ObjectKey{Name: "bar-1", Namespace: namespace}, bar)).To(Succeed())
+			bar.Finalizers = []string{"example.com/finalizer"}
+			bar.Annotations = map[string]string{v1alpha1.DrainedAnnotation: "true"}
+			Expect(h.Client.Update(h.Context, bar)).To(Succeed())
+		})
+
+		It("keeps the Foo and reports the remaining Bars until they are gone", func() {
+			result := h.Run(namespace, operations(newAdapter(loader.NewLoader()))...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Foos).To(HaveLen(1))
+
+			condition := apimeta.FindStatusCondition(result.GetFoo(foo.Name).Status.Conditions, "Terminating")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(v1alpha1.BarsRemainingReason.String()))
+			Expect(condition.Message).To(ContainSubstring("bar-1"))
+		})
+
+		It("removes the finalizer with a warning once the finalization timeout is reached", func() {
+			a := newAdapter(loader.NewLoader())
+			a.finalizationTimeout = time.Nanosecond
+
+			result := h.Run(namespace, operations(a)...)
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Foos).To(BeEmpty())
+			Expect(result.Bars).To(HaveLen(1))
+			Expect(h.Recorder.Events).To(Receive(ContainSubstring(FinalizationTimedOutEventReason)))
+		})
+	})
+})
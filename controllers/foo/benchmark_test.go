@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package foo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BenchmarkFinalization measures the finalization of a Foo resource with thousands of Bar replicas, reporting the
+// number of requests sent to the API server along with the time it takes. The replicas are given no time to drain, so
+// they don't contain the drain finalizer and the Foo resource is released in a single reconcile. The benchmark fails
+// if the number of requests grows with the number of replicas.
+func BenchmarkFinalization(b *testing.B) {
+	var baseline float64
+	for _, replicas := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("%d replicas", replicas), func(b *testing.B) {
+			var requests int
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				h, foo := newFinalizationHarness(replicas)
+				counter := harness.NewCountingClient(h.Client)
+				a := NewAdapter(h.Context, counter, foo, nil, loader.NewLoader(), h.Recorder, h.Logger)
+				b.StartTimer()
+
+				result := h.Run(foo.Namespace, a.EnsureFinalizersAreCalled)
+				if result.Err != nil {
+					b.Fatal(result.Err)
+				}
+				if len(result.Foos) != 0 || len(result.Bars) != 0 {
+					b.Fatalf("Foo not finalized, %d Bars remaining", len(result.Bars))
+				}
+				requests += counter.Total()
+			}
+			requestsPerOp := float64(requests) / float64(b.N)
+			b.ReportMetric(requestsPerOp, "requests/op")
+
+			if baseline == 0 {
+				baseline = requestsPerOp
+			} else if requestsPerOp != baseline {
+				b.Fatalf("%v requests/op with %d replicas, %v with fewer replicas", requestsPerOp, replicas, baseline)
+			}
+		})
+	}
+}
+
+// newFinalizationHarness returns a harness containing a Foo resource being deleted and the given number of Bar
+// replicas, along with the Foo resource.
+func newFinalizationHarness(replicas int) (*harness.Harness, *v1alpha1.Foo) {
+	var gracePeriod int64
+	foo := harness.NewFoo("foo", "default").WithDesiredReplicas(replicas).WithFinalizers(defaultFinalizerName).Build()
+	foo.Spec.TerminationGracePeriodSeconds = &gracePeriod
+
+	objects := []client.Object{foo}
+	for i := 0; i < replicas; i++ {
+		objects = append(objects, harness.NewBar(fmt.Sprintf("foo-%05d", i), foo.Namespace).ForFoo(foo).Build())
+	}
+
+	h := harness.New(objects...)
+	if err := h.Client.Delete(h.Context, foo); err != nil {
+		panic(err)
+	}
+	h.Reload(foo)
+
+	return h, foo
+}
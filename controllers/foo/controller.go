@@ -18,33 +18,61 @@ package foo
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/cache"
 	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/multicluster"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit-example/sharding"
+	"github.com/konflux-ci/operator-toolkit-example/tracing"
 	"github.com/konflux-ci/operator-toolkit/controller"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // Controller reconciles a Foo object
 type Controller struct {
-	client client.Client
-	log    logr.Logger
+	client                  client.Client
+	drainFinalizerName      string
+	enforceQuotas           bool
+	finalizationTimeout     time.Duration
+	finalizerDomain         string
+	finalizerName           string
+	hub                     *multicluster.Hub
+	log                     logr.Logger
+	maxConcurrentReconciles int
+	maxReplicaStatuses      int
+	namespaces              operatorconfig.NamespaceFilter
+	recorder                record.EventRecorder
+	sharder                 *sharding.Sharder
 }
 
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=foos,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=foos/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=foos/finalizers,verbs=update
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=bars,verbs=deletecollection
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := c.log.WithValues("Foo", req.NamespacedName)
+	ctx, span := tracing.StartReconcile(ctx, "Foo", req.Namespace, req.Name)
+	defer span.End()
+
+	logger := tracing.WithTraceID(ctx, c.log.WithValues("Foo", req.NamespacedName))
 
 	foo := &v1alpha1.Foo{}
 	err := c.client.Get(ctx, req.NamespacedName, foo)
@@ -56,33 +84,182 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
-	adapter := NewAdapter(ctx, c.client, foo, loader.NewLoader(), &logger)
+	// The bucket of the Foo might have been handed over to another replica after the request was queued
+	if !c.sharder.Owns(foo) {
+		return ctrl.Result{}, nil
+	}
+
+	// The snapshot loader ensures the Bars of the Foo are only listed once per reconcile
+	adapter := NewAdapter(ctx, c.client, foo, c.hub, loader.NewSnapshotLoader(loader.NewLoader()), c.recorder, &logger)
+	if c.finalizerName != "" {
+		adapter.drainFinalizerName = c.drainFinalizerName
+		adapter.finalizerDomain = c.finalizerDomain
+		adapter.finalizerName = c.finalizerName
+	}
+	adapter.enforceQuotas = c.enforceQuotas
+	adapter.finalizationTimeout = c.finalizationTimeout
+	adapter.maxReplicaStatuses = c.maxReplicaStatuses
 
-	return controller.ReconcileHandler([]controller.Operation{
+	return controller.ReconcileHandler(tracing.TraceOperations(&adapter.Context,
 		adapter.EnsureFinalizersAreCalled,
 		adapter.EnsureFinalizerIsAdded,
 		adapter.EnsureMaximumReplicas,
+		adapter.EnsureUnplacedReplicasAreDeleted,
 		adapter.EnsureMinimumReplicas,
+		adapter.EnsureDriftIsHandled,
 		adapter.EnsureReplicaDataConsistency,
-	})
+		adapter.EnsureReconcileRequestIsHandled,
+		adapter.EnsureDrainedReplicasAreDeleted,
+		adapter.EnsureResyncIsScheduled,
+	))
 }
 
-// Register registers the controller with the passed manager and log.
-func (c *Controller) Register(mgr ctrl.Manager, log *logr.Logger, _ cluster.Cluster) error {
-	c.client = mgr.GetClient()
-	c.log = log.WithName("foo")
+// Configure applies the Foo controller configuration, the finalizer domain, names and timeout, the maximum number of replica
+// statuses, the watched namespaces and the FooQuotas feature gate set in the operator configuration.
+func (c *Controller) Configure(config *operatorconfig.OperatorConfig) {
+	c.drainFinalizerName = config.GetDrainFinalizerName()
+	c.enforceQuotas = config.IsFeatureEnabled(operatorconfig.FooQuotasFeature)
+	c.finalizationTimeout = config.FinalizationTimeout.Duration
+	c.finalizerDomain = config.FinalizerDomain
+	c.finalizerName = config.GetFinalizerName()
+	c.maxConcurrentReconciles = config.GetControllerConfig(operatorconfig.FooController).MaxConcurrentReconciles
+	c.maxReplicaStatuses = config.MaxReplicaStatuses
+	c.namespaces = config.GetNamespaceFilter()
+}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Foo{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
-		Owns(&v1alpha1.Bar{}).
-		Complete(c)
+// SetSharder sets the Sharder used to reconcile only the Foo resources in the buckets held by the replica.
+func (c *Controller) SetSharder(sharder *sharding.Sharder) {
+	c.sharder = sharder
 }
 
-// SetupCache indexes the Bar spec.foo field, so it is possible to list filtering by that field.
+// Register registers the controller with the passed manager and log. When the passed cluster is a multicluster.Hub,
+// the Bar resources in every member cluster are watched as well.
+func (c *Controller) Register(mgr ctrl.Manager, log *logr.Logger, hub cluster.Cluster) error {
+	c.client = tracing.NewClient(mgr.GetClient())
+	c.log = log.WithName(operatorconfig.FooController)
+	c.hub, _ = hub.(*multicluster.Hub)
+	c.recorder = mgr.GetEventRecorderFor("foo-controller")
+
+	// Annotating a Foo triggers a reconcile, so it can be requested manually without changing the spec. Bars are mapped
+	// to their owner and to the Foos in their spec.foo field and Foo label, as they might not be owned by them yet. The
+	// namespace predicate is not an event filter, as it would drop the bucket leases living in the namespace of the
+	// operator. Neither is the sharder predicate, as the Foos a Bar is mapped to might be in different buckets, so the
+	// mapped requests are filtered instead
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Foo{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}), c.namespaces.Predicate(),
+			c.sharder.Predicate())).
+		Watches(&source.Kind{Type: &v1alpha1.Bar{}}, handler.EnqueueRequestsFromMapFunc(c.mapBarToFoo),
+			builder.WithPredicates(c.namespaces.Predicate())).
+		WithOptions(crcontroller.Options{MaxConcurrentReconciles: c.maxConcurrentReconciles})
+
+	// The Foo resources in a bucket are reconciled when the replica takes it over
+	if c.sharder != nil {
+		controllerBuilder = controllerBuilder.Watches(c.sharder.Source(),
+			handler.EnqueueRequestsFromMapFunc(c.mapLeaseToFoos))
+	}
+
+	fooController, err := controllerBuilder.Build(c)
+	if err != nil || c.hub == nil {
+		return err
+	}
+
+	// Bars in member clusters can't be owned by the Foo, so they are mapped the same way. Member clusters loaded
+	// after the controller is registered are indexed and watched as soon as they are added to the hub, and their
+	// events dropped once they are removed or replaced
+	return c.hub.WatchMembers(func(ctx context.Context, _ string, member cluster.Cluster) error {
+		err := cache.SetupBarCache(member.GetCache())
+		if err != nil {
+			return err
+		}
+
+		return fooController.Watch(source.NewKindWithCache(&v1alpha1.Bar{}, member.GetCache()),
+			handler.EnqueueRequestsFromMapFunc(c.mapBarToFoo), multicluster.MemberPredicate(ctx),
+			c.namespaces.Predicate())
+	})
+}
+
+// SetupCache indexes the Bar resources by the UID of their owner, so it is possible to list the Bar resources of a Foo
+// resource filtering by it.
 func (c *Controller) SetupCache(mgr ctrl.Manager) error {
-	indexFunc := func(obj client.Object) []string {
-		return []string{obj.(*v1alpha1.Bar).Spec.Foo}
+	return cache.SetupBarCache(mgr.GetCache())
+}
+
+// mapLeaseToFoos returns a reconcile request for every Foo resource in the bucket of the given lease.
+func (c *Controller) mapLeaseToFoos(lease client.Object) []reconcile.Request {
+	foos := &v1alpha1.FooList{}
+	err := c.client.List(context.Background(), foos)
+	if err != nil {
+		c.log.Error(err, "unable to list the Foo resources of the acquired bucket")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range foos.Items {
+		if c.sharder.InBucket(&foos.Items[i], lease) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&foos.Items[i])})
+		}
+	}
+
+	return requests
+}
+
+// mapBarToFoo returns a reconcile request for the Foo resource owning the given Bar resource, which is the one listing
+// it as one of its replicas, and for the Foo resources in its spec.foo field and Foo label, as the Bar might not be
+// owned by them yet. The names are read from the Bar itself, so no index is needed to map it. Only the Foo resources
+// in the buckets held by the replica are requested.
+func (c *Controller) mapBarToFoo(obj client.Object) []reconcile.Request {
+	bar := obj.(*v1alpha1.Bar)
+
+	var requests []reconcile.Request
+	for _, name := range []string{c.getOwnerName(bar), bar.Spec.Foo, bar.Labels[v1alpha1.FooLabel]} {
+		if name == "" || containsRequest(requests, name) {
+			continue
+		}
+		key := types.NamespacedName{Name: name, Namespace: bar.Namespace}
+		if !c.sharder.Owns(&v1alpha1.Foo{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+
+	return requests
+}
+
+// getOwnerName returns the name of the Foo resource owning the given Bar resource, or an empty string if it has no
+// owner. Bars in member clusters only carry the UID of their owner, so the Foo resources in the namespace of the Bar
+// are looked up to find its name.
+func (c *Controller) getOwnerName(bar *v1alpha1.Bar) string {
+	uid := bar.GetFooUID()
+	if uid == "" {
+		return ""
+	}
+	if owner := metav1.GetControllerOf(bar); owner != nil && owner.UID == uid {
+		return owner.Name
+	}
+
+	foos := &v1alpha1.FooList{}
+	err := c.client.List(context.Background(), foos, client.InNamespace(bar.Namespace))
+	if err != nil {
+		c.log.Error(err, "unable to list the Foo resources to find the owner of a Bar", "Bar", bar.Name)
+		return ""
+	}
+	for i := range foos.Items {
+		if foos.Items[i].UID == uid {
+			return foos.Items[i].Name
+		}
+	}
+
+	return ""
+}
+
+// containsRequest returns true if one of the given reconcile requests is for the resource with the given name.
+func containsRequest(requests []reconcile.Request, name string) bool {
+	for _, request := range requests {
+		if request.Name == name {
+			return true
+		}
 	}
 
-	return mgr.GetCache().IndexField(context.Background(), &v1alpha1.Bar{}, "spec.foo", indexFunc)
+	return false
 }
@@ -0,0 +1,141 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package foo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/sharding"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Foo controller", func() {
+	const namespace = "default"
+
+	var c *Controller
+
+	BeforeEach(func() {
+		h := harness.New()
+		c = &Controller{client: h.Client, log: *h.Logger}
+	})
+
+	It("maps Bars to the Foo in their spec even if they aren't owned by it", func() {
+		foo := harness.NewFoo("foo", namespace).Build()
+		bar := harness.NewBar("bar", namespace).ForFoo(foo).Build()
+
+		Expect(c.mapBarToFoo(bar)).To(ConsistOf(reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: foo.Name, Namespace: namespace},
+		}))
+	})
+
+	It("maps Bars to the Foo in their label as well when it differs from the one in their spec", func() {
+		foo := harness.NewFoo("foo", namespace).Build()
+		bar := harness.NewBar("bar", namespace).ForFoo(foo).WithLabels(map[string]string{
+			v1alpha1.FooLabel: "previous",
+		}).Build()
+
+		Expect(c.mapBarToFoo(bar)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: foo.Name, Namespace: namespace}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "previous", Namespace: namespace}},
+		))
+	})
+
+	It("maps Bars to their owner when neither their spec nor their label point to it", func() {
+		foo := harness.NewFoo("foo", namespace).Build()
+		bar := harness.NewBar("bar", namespace).ForFoo(foo).WithOwner(foo).WithLabels(map[string]string{
+			v1alpha1.FooLabel: "label",
+		}).Build()
+		bar.Spec.Foo = "spec"
+
+		Expect(c.mapBarToFoo(bar)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: foo.Name, Namespace: namespace}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "label", Namespace: namespace}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "spec", Namespace: namespace}},
+		))
+	})
+
+	It("maps Bars only labeled with the UID of their owner to it", func() {
+		foo := harness.NewFoo("foo", namespace).Build()
+		h := harness.New(foo)
+		c.client = h.Client
+		bar := harness.NewBar("bar", namespace).WithLabels(map[string]string{
+			v1alpha1.FooUIDLabel: string(foo.UID),
+		}).Build()
+
+		Expect(c.mapBarToFoo(bar)).To(ConsistOf(reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: foo.Name, Namespace: namespace},
+		}))
+	})
+
+	It("maps Bars to the Foos in the buckets held by the replica, whatever the bucket of the Foo in their spec", func() {
+		// Another replica is alive, so the replica only holds the first of the two buckets
+		holder := "replica-b"
+		h := harness.New(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-member-replica-b",
+				Namespace: namespace,
+				Labels:    map[string]string{sharding.GroupLabel: "test", sharding.MemberLabel: "true"},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity: &holder,
+				RenewTime:      &metav1.MicroTime{Time: time.Now().Add(time.Hour)},
+			},
+		})
+		c.sharder = sharding.NewSharder(h.Client, h.Client, sharding.Options{
+			Buckets:       2,
+			Identity:      "replica-a",
+			LeaseDuration: time.Minute,
+			Name:          "test",
+			Namespace:     namespace,
+			RenewPeriod:   time.Hour,
+		})
+		ctx, cancel := context.WithCancel(h.Context)
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(c.sharder.Start(ctx)).To(Succeed())
+		}()
+		Eventually(c.sharder.GetOwnedBuckets).Should(Equal([]int{0}))
+
+		// Looks for the names of Foos in every bucket
+		names := map[int]string{}
+		for i := 0; len(names) < 2; i++ {
+			name := fmt.Sprintf("foo-%d", i)
+			names[c.sharder.GetBucket(harness.NewFoo(name, namespace).Build())] = name
+		}
+		bar := harness.NewBar("bar", namespace).WithLabels(map[string]string{v1alpha1.FooLabel: names[0]}).Build()
+		bar.Spec.Foo = names[1]
+
+		Expect(c.mapBarToFoo(bar)).To(ConsistOf(reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: names[0], Namespace: namespace},
+		}))
+	})
+
+	It("doesn't map Bars without a Foo", func() {
+		Expect(c.mapBarToFoo(harness.NewBar("bar", namespace).Build())).To(BeEmpty())
+	})
+})
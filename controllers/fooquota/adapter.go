@@ -0,0 +1,51 @@
+package fooquota
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/adapters"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Adapter holds the objects needed to reconcile a FooQuota resource.
+type adapter struct {
+	adapters.Adapter[*v1alpha1.FooQuota, loader.ObjectLoader]
+	fooQuota *v1alpha1.FooQuota // this is the kind of resource this adapter reconciles
+}
+
+// NewAdapter creates and returns an Adapter instance.
+func NewAdapter(ctx context.Context, client client.Client, fooQuota *v1alpha1.FooQuota, loader loader.ObjectLoader, logger *logr.Logger) *adapter {
+	return &adapter{
+		Adapter:  adapters.New(ctx, client, fooQuota, loader, logger),
+		fooQuota: fooQuota,
+	}
+}
+
+// EnsureUsageIsReported is an operation that will ensure that the number of Foo and Bar resources in the namespace
+// is reported in the FooQuota resource's status.
+func (a *adapter) EnsureUsageIsReported() (controller.OperationResult, error) {
+	foos, err := a.Loader.GetFoos(a.Context, a.Client, a.fooQuota.Namespace)
+	if err != nil {
+		return controller.RequeueWithError(err)
+	}
+
+	bars, err := a.Loader.GetNamespaceBars(a.Context, a.Client, a.fooQuota.Namespace)
+	if err != nil {
+		return controller.RequeueWithError(err)
+	}
+
+	if a.fooQuota.Status.Used.Foos == len(foos) && a.fooQuota.Status.Used.Bars == len(bars) {
+		return controller.ContinueProcessing()
+	}
+
+	return controller.RequeueOnErrorOrContinue(a.PatchStatus(func() {
+		a.fooQuota.Status.Used = v1alpha1.FooQuotaUsage{
+			Bars: len(bars),
+			Foos: len(foos),
+		}
+	}))
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fooquota
+
+import (
+	"fmt"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FooQuota adapter", func() {
+	const namespace = "default"
+
+	var (
+		foo   *v1alpha1.Foo
+		h     *harness.Harness
+		quota *v1alpha1.FooQuota
+	)
+
+	newAdapter := func(objectLoader loader.ObjectLoader) *adapter {
+		Expect(h.Client.Get(h.Context, client.ObjectKeyFromObject(quota), quota)).To(Succeed())
+		return NewAdapter(h.Context, h.Client, quota, objectLoader, h.Logger)
+	}
+
+	getQuota := func() *v1alpha1.FooQuota {
+		reconciled := &v1alpha1.FooQuota{}
+		Expect(h.Client.Get(h.Context, client.ObjectKeyFromObject(quota), reconciled)).To(Succeed())
+		return reconciled
+	}
+
+	BeforeEach(func() {
+		foo = harness.NewFoo("foo", namespace).Build()
+		quota = &v1alpha1.FooQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: namespace}}
+	})
+
+	It("reports the number of Foos and Bars in the namespace", func() {
+		h = harness.New(quota, foo,
+			harness.NewFoo("other", namespace).Build(),
+			harness.NewBar("bar-1", namespace).ForFoo(foo).Build(),
+			harness.NewBar("bar-2", namespace).ForFoo(foo).Build(),
+			harness.NewBar("bar-3", namespace).Build(),
+			harness.NewFoo("elsewhere", "other-namespace").Build(),
+		)
+		a := newAdapter(loader.NewLoader())
+
+		result := h.Run(namespace, a.EnsureUsageIsReported)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(getQuota().Status.Used).To(Equal(v1alpha1.FooQuotaUsage{Bars: 3, Foos: 2}))
+	})
+
+	It("doesn't patch the status when the usage didn't change", func() {
+		quota.Status.Used = v1alpha1.FooQuotaUsage{Bars: 0, Foos: 1}
+		h = harness.New(quota, foo)
+		a := newAdapter(loader.NewLoader())
+		resourceVersion := quota.ResourceVersion
+
+		result := h.Run(namespace, a.EnsureUsageIsReported)
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(getQuota().ResourceVersion).To(Equal(resourceVersion))
+	})
+
+	It("requeues with an error when the Foos can't be loaded", func() {
+		h = harness.New(quota)
+		a := newAdapter(loader.NewMockLoader().
+			OnGetFoos().WithArgs(namespace).Return(nil, fmt.Errorf("connection refused")))
+
+		result := h.Run(namespace, a.EnsureUsageIsReported)
+		Expect(result.Err).To(MatchError("connection refused"))
+	})
+
+	Context("when reconciled by the controller", func() {
+		var c *Controller
+
+		BeforeEach(func() {
+			h = harness.New(quota, foo, harness.NewBar("bar", namespace).ForFoo(foo).Build())
+			c = &Controller{client: h.Client, log: *h.Logger}
+		})
+
+		It("reports the usage of the namespace", func() {
+			result, err := c.Reconcile(h.Context, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(quota)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(getQuota().Status.Used).To(Equal(v1alpha1.FooQuotaUsage{Bars: 1, Foos: 1}))
+		})
+
+		It("maps the Foos and Bars to every FooQuota in their namespace", func() {
+			Expect(c.mapToFooQuotas(foo)).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: quota.Name, Namespace: namespace},
+			}))
+			Expect(c.mapToFooQuotas(harness.NewBar("bar", "other-namespace").Build())).To(BeEmpty())
+		})
+	})
+})
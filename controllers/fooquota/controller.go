@@ -0,0 +1,166 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fooquota
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/loader"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit-example/sharding"
+	"github.com/konflux-ci/operator-toolkit-example/tracing"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Controller reconciles a FooQuota object
+type Controller struct {
+	client                  client.Client
+	log                     logr.Logger
+	maxConcurrentReconciles int
+	namespaces              operatorconfig.NamespaceFilter
+	sharder                 *sharding.Sharder
+}
+
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=fooquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=appstudio.redhat.com,resources=fooquotas/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.StartReconcile(ctx, "FooQuota", req.Namespace, req.Name)
+	defer span.End()
+
+	logger := tracing.WithTraceID(ctx, c.log.WithValues("FooQuota", req.NamespacedName))
+
+	fooQuota := &v1alpha1.FooQuota{}
+	err := c.client.Get(ctx, req.NamespacedName, fooQuota)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// The bucket of the FooQuota might have been handed over to another replica after the request was queued
+	if !c.sharder.Owns(fooQuota) {
+		return ctrl.Result{}, nil
+	}
+
+	adapter := NewAdapter(ctx, c.client, fooQuota, loader.NewLoader(), &logger)
+
+	return controller.ReconcileHandler(tracing.TraceOperations(&adapter.Context,
+		adapter.EnsureUsageIsReported,
+	))
+}
+
+// Configure applies the FooQuota controller configuration and the watched namespaces set in the operator
+// configuration.
+func (c *Controller) Configure(config *operatorconfig.OperatorConfig) {
+	c.maxConcurrentReconciles = config.GetControllerConfig(operatorconfig.FooQuotaController).MaxConcurrentReconciles
+	c.namespaces = config.GetNamespaceFilter()
+}
+
+// SetSharder sets the Sharder used to reconcile only the FooQuota resources in the buckets held by the replica.
+func (c *Controller) SetSharder(sharder *sharding.Sharder) {
+	c.sharder = sharder
+}
+
+// Register registers the controller with the passed manager and log.
+func (c *Controller) Register(mgr ctrl.Manager, log *logr.Logger, _ cluster.Cluster) error {
+	c.client = tracing.NewClient(mgr.GetClient())
+	c.log = log.WithName(operatorconfig.FooQuotaController)
+
+	// Updates don't change the namespace usage, so only creations and deletions are relevant
+	usageChangedPredicate := predicate.Funcs{
+		UpdateFunc: func(event.UpdateEvent) bool {
+			return false
+		},
+	}
+
+	// Foos and Bars are not filtered by the sharder, as their bucket is unrelated to the one of their FooQuotas, which
+	// are filtered when mapped instead
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.FooQuota{}, builder.WithPredicates(c.namespaces.Predicate(), c.sharder.Predicate())).
+		WithOptions(crcontroller.Options{MaxConcurrentReconciles: c.maxConcurrentReconciles}).
+		Watches(&source.Kind{Type: &v1alpha1.Foo{}}, handler.EnqueueRequestsFromMapFunc(c.mapToFooQuotas),
+			builder.WithPredicates(c.namespaces.Predicate(), usageChangedPredicate)).
+		Watches(&source.Kind{Type: &v1alpha1.Bar{}}, handler.EnqueueRequestsFromMapFunc(c.mapToFooQuotas),
+			builder.WithPredicates(c.namespaces.Predicate(), usageChangedPredicate))
+
+	// The FooQuota resources in a bucket are reconciled when the replica takes it over
+	if c.sharder != nil {
+		controllerBuilder = controllerBuilder.Watches(c.sharder.Source(),
+			handler.EnqueueRequestsFromMapFunc(c.mapLeaseToFooQuotas))
+	}
+
+	return controllerBuilder.Complete(c)
+}
+
+// mapLeaseToFooQuotas returns a reconcile request for every FooQuota resource in the bucket of the given lease.
+func (c *Controller) mapLeaseToFooQuotas(lease client.Object) []reconcile.Request {
+	quotas := &v1alpha1.FooQuotaList{}
+	err := c.client.List(context.Background(), quotas)
+	if err != nil {
+		c.log.Error(err, "unable to list the FooQuota resources of the acquired bucket")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range quotas.Items {
+		if c.sharder.InBucket(&quotas.Items[i], lease) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&quotas.Items[i])})
+		}
+	}
+
+	return requests
+}
+
+// mapToFooQuotas returns a reconcile request for every FooQuota resource in the namespace of the given object owned by
+// the replica.
+func (c *Controller) mapToFooQuotas(obj client.Object) []reconcile.Request {
+	quotas, err := loader.NewLoader().GetFooQuotas(context.Background(), c.client, obj.GetNamespace())
+	if err != nil {
+		c.log.Error(err, "Failed to list FooQuotas", "Namespace", obj.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range quotas {
+		if c.sharder.Owns(&quotas[i]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: quotas[i].Name, Namespace: quotas[i].Namespace},
+			})
+		}
+	}
+
+	return requests
+}
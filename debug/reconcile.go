@@ -0,0 +1,184 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReconcilePath is the path the reconcile trigger is served at
+const ReconcilePath = "/debug/reconcile"
+
+// ReconcileHandler is an http.Handler requesting the reconcile of the Foo or Bar resource passed in the kind, namespace
+// and name query parameters of a POST request. The reconcile is requested by setting the
+// v1alpha1.ReconcileRequestedAtAnnotation of the resource to the current time, so the replica reconciling it picks it
+// up whatever the replica serving the request is, and the value is returned so the caller can wait for it to be
+// reported in the status.
+//
+// Callers authenticate with a bearer token, which is validated with a TokenReview, and have to be allowed to patch the
+// resource, which is checked with a SubjectAccessReview. The trigger grants no more than annotating the resource does.
+type ReconcileHandler struct {
+	client client.Client
+	log    logr.Logger
+	now    func() time.Time
+}
+
+// reconcilableKind is a kind of resource whose reconcile can be requested.
+type reconcilableKind struct {
+	newObject func() client.Object
+	resource  string
+}
+
+// reconcilableKinds contains the kinds whose reconcile can be requested, keyed by their lowercase name
+var reconcilableKinds = map[string]reconcilableKind{
+	"bar": {newObject: func() client.Object { return &v1alpha1.Bar{} }, resource: "bars"},
+	"foo": {newObject: func() client.Object { return &v1alpha1.Foo{} }, resource: "foos"},
+}
+
+// NewReconcileHandler creates and returns a ReconcileHandler using the given client to review the callers and
+// annotate the resources.
+func NewReconcileHandler(cli client.Client, log logr.Logger) *ReconcileHandler {
+	return &ReconcileHandler{
+		client: cli,
+		log:    log,
+		now:    time.Now,
+	}
+}
+
+// ServeHTTP requests the reconcile of the resource passed in the request once the caller is authenticated and allowed
+// to patch it.
+func (h *ReconcileHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	kindName, namespace, name := strings.ToLower(query.Get("kind")), query.Get("namespace"), query.Get("name")
+	kind, found := reconcilableKinds[kindName]
+	if !found || namespace == "" || name == "" {
+		http.Error(w, "the kind (foo or bar), namespace and name query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	token, found := getBearerToken(req)
+	if !found {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+
+	tokenReview := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	err := h.client.Create(req.Context(), tokenReview)
+	if err != nil {
+		h.log.Error(err, "unable to review the token of a reconcile request")
+		http.Error(w, "unable to review the token", http.StatusInternalServerError)
+		return
+	}
+	if !tokenReview.Status.Authenticated {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+	user := tokenReview.Status.User
+
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "patch",
+				Group:     v1alpha1.GroupVersion.Group,
+				Resource:  kind.resource,
+				Name:      name,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			Extra:  getExtra(user.Extra),
+			UID:    user.UID,
+		},
+	}
+	err = h.client.Create(req.Context(), accessReview)
+	if err != nil {
+		h.log.Error(err, "unable to review the access of a reconcile request")
+		http.Error(w, "unable to review the access", http.StatusInternalServerError)
+		return
+	}
+	if !accessReview.Status.Allowed {
+		http.Error(w, fmt.Sprintf("%s is not allowed to patch %s %s/%s", user.Username, kind.resource, namespace, name),
+			http.StatusForbidden)
+		return
+	}
+
+	obj := kind.newObject()
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	requestedAt := h.now().UTC().Format(time.RFC3339Nano)
+	obj.SetAnnotations(map[string]string{v1alpha1.ReconcileRequestedAtAnnotation: requestedAt})
+
+	err = h.client.Patch(req.Context(), obj, patch)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("%s %s/%s not found", kindName, namespace, name), http.StatusNotFound)
+			return
+		}
+
+		h.log.Error(err, "unable to request the reconcile", "Kind", kindName, "Namespace", namespace, "Name", name)
+		http.Error(w, "unable to request the reconcile", http.StatusInternalServerError)
+		return
+	}
+	h.log.Info("Reconcile requested", "Kind", kindName, "Namespace", namespace, "Name", name,
+		"User", user.Username, "RequestedAt", requestedAt)
+
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprintln(w, requestedAt)
+}
+
+// getBearerToken returns the bearer token in the Authorization header of the given request, if any.
+func getBearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+
+	return token, token != ""
+}
+
+// getExtra converts the extra information of an authenticated user to the one expected by a SubjectAccessReview.
+func getExtra(extra map[string]authenticationv1.ExtraValue) map[string]authorizationv1.ExtraValue {
+	if extra == nil {
+		return nil
+	}
+
+	converted := make(map[string]authorizationv1.ExtraValue, len(extra))
+	for key, value := range extra {
+		converted[key] = authorizationv1.ExtraValue(value)
+	}
+
+	return converted
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// reviewingClient answers the TokenReviews and SubjectAccessReviews the way the API server would, authenticating the
+// "valid" token as the "on-call" user and only allowing the users in allowedUsers.
+type reviewingClient struct {
+	client.Client
+	allowedUsers []string
+}
+
+func (c *reviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		if review.Spec.Token == "valid" {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: "on-call"}
+		}
+		return nil
+	case *authorizationv1.SubjectAccessReview:
+		for _, user := range c.allowedUsers {
+			review.Status.Allowed = review.Status.Allowed || review.Spec.User == user
+		}
+		return nil
+	}
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+var _ = Describe("ReconcileHandler", func() {
+	const namespace = "default"
+
+	var (
+		cli     *reviewingClient
+		foo     *v1alpha1.Foo
+		h       *harness.Harness
+		handler *ReconcileHandler
+	)
+
+	serve := func(method, query, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, ReconcilePath+"?"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	BeforeEach(func() {
+		foo = harness.NewFoo("foo", namespace).Build()
+		h = harness.New(foo)
+		cli = &reviewingClient{Client: h.Client, allowedUsers: []string{"on-call"}}
+		handler = NewReconcileHandler(cli, *h.Logger)
+		handler.now = func() time.Time {
+			return time.Date(2023, 5, 4, 10, 0, 0, 0, time.UTC)
+		}
+	})
+
+	It("requests the reconcile of the resource and returns the annotation value", func() {
+		response := serve(http.MethodPost, "kind=Foo&namespace=default&name=foo", "valid")
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+		Expect(response.Body.String()).To(Equal("2023-05-04T10:00:00Z\n"))
+
+		h.Reload(foo)
+		Expect(foo.Annotations).To(HaveKeyWithValue(v1alpha1.ReconcileRequestedAtAnnotation, "2023-05-04T10:00:00Z"))
+	})
+
+	It("only accepts POST requests", func() {
+		response := serve(http.MethodGet, "kind=foo&namespace=default&name=foo", "valid")
+		Expect(response.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(response.Header().Get("Allow")).To(Equal(http.MethodPost))
+	})
+
+	It("rejects requests for unknown kinds or without a namespace and a name", func() {
+		Expect(serve(http.MethodPost, "kind=fooquota&namespace=default&name=foo", "valid").Code).To(
+			Equal(http.StatusBadRequest))
+		Expect(serve(http.MethodPost, "kind=foo&name=foo", "valid").Code).To(Equal(http.StatusBadRequest))
+		Expect(serve(http.MethodPost, "kind=foo&namespace=default", "valid").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects requests without a valid bearer token", func() {
+		Expect(serve(http.MethodPost, "kind=foo&namespace=default&name=foo", "").Code).To(
+			Equal(http.StatusUnauthorized))
+		Expect(serve(http.MethodPost, "kind=foo&namespace=default&name=foo", "invalid").Code).To(
+			Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects users not allowed to patch the resource", func() {
+		cli.allowedUsers = nil
+
+		response := serve(http.MethodPost, "kind=foo&namespace=default&name=foo", "valid")
+		Expect(response.Code).To(Equal(http.StatusForbidden))
+
+		h.Reload(foo)
+		Expect(foo.Annotations).NotTo(HaveKey(v1alpha1.ReconcileRequestedAtAnnotation))
+	})
+
+	It("returns not found for missing resources", func() {
+		response := serve(http.MethodPost, "kind=bar&namespace=default&name=missing", "valid")
+		Expect(response.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,134 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package finalizers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reservedName is the name of the finalizer deleting the Bar resources of a Foo resource, which is built into the
+// Foo adapter and can't be used by handlers
+const reservedName = "finalizer"
+
+// DefaultBackoff is the backoff used to retry failed handlers before giving up until the next reconcile.
+var DefaultBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Steps:    3,
+}
+
+// DefaultRegistry is the registry the Foo controller runs the handlers of. Features register their handlers into it
+// when initialized.
+var DefaultRegistry = NewRegistry()
+
+type (
+	// Handler is a cleanup step run when a Foo resource is deleted, e.g. to deregister it from an external system. It
+	// must be idempotent, as it runs again if it fails or the finalizer can't be removed afterwards.
+	Handler func(ctx context.Context, cli client.Client, foo *v1alpha1.Foo) error
+
+	// Registry holds the named finalizer handlers. Every handler gets its own finalizer, named after the handler
+	// within the finalizer domain, so it keeps the Foo resource until it succeeds.
+	Registry struct {
+		handlers map[string]Handler
+		mutex    sync.RWMutex
+	}
+)
+
+// NewRegistry creates and returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: map[string]Handler{},
+	}
+}
+
+// Register adds the given handler to the DefaultRegistry.
+func Register(name string, handler Handler) error {
+	return DefaultRegistry.Register(name, handler)
+}
+
+// Register adds the given handler to the registry. The name has to be a DNS label, as it is used in the finalizer,
+// and can't be registered twice.
+func (r *Registry) Register(name string, handler Handler) error {
+	if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+		return fmt.Errorf("invalid finalizer handler name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	if name == reservedName {
+		return fmt.Errorf("finalizer handler name %q is reserved", name)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, found := r.handlers[name]; found {
+		return fmt.Errorf("finalizer handler %q is already registered", name)
+	}
+	r.handlers[name] = handler
+
+	return nil
+}
+
+// GetNames returns the sorted names of the registered handlers. A nil Registry has no handlers.
+func (r *Registry) GetNames() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Run runs the handler with the given name for the given Foo resource, retrying it with the DefaultBackoff if it
+// fails. The error of the last try is returned.
+func (r *Registry) Run(ctx context.Context, name string, cli client.Client, foo *v1alpha1.Foo) error {
+	r.mutex.RLock()
+	handler, found := r.handlers[name]
+	r.mutex.RUnlock()
+
+	if !found {
+		return fmt.Errorf("unknown finalizer handler %q", name)
+	}
+
+	return retry.OnError(DefaultBackoff, func(error) bool {
+		return ctx.Err() == nil
+	}, func() error {
+		return handler(ctx, cli, foo)
+	})
+}
+
+// GetFinalizerName returns the name of the finalizer of the handler with the given name within the given domain.
+func GetFinalizerName(domain, name string) string {
+	return domain + "/" + name
+}
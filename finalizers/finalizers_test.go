@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package finalizers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var registry *Registry
+
+	noop := func(context.Context, client.Client, *v1alpha1.Foo) error {
+		return nil
+	}
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+	})
+
+	It("returns the sorted names of the registered handlers", func() {
+		Expect(registry.Register("metrics", noop)).To(Succeed())
+		Expect(registry.Register("backup", noop)).To(Succeed())
+
+		Expect(registry.GetNames()).To(Equal([]string{"backup", "metrics"}))
+	})
+
+	It("rejects invalid, reserved and duplicated names", func() {
+		Expect(registry.Register("Not_A_Label", noop)).NotTo(Succeed())
+		Expect(registry.Register("finalizer", noop)).NotTo(Succeed())
+		Expect(registry.Register("backup", noop)).To(Succeed())
+		Expect(registry.Register("backup", noop)).NotTo(Succeed())
+	})
+
+	It("retries failed handlers", func() {
+		var calls int
+		Expect(registry.Register("flaky", func(context.Context, client.Client, *v1alpha1.Foo) error {
+			calls++
+			if calls < 2 {
+				return fmt.Errorf("temporary error")
+			}
+			return nil
+		})).To(Succeed())
+
+		Expect(registry.Run(context.TODO(), "flaky", nil, &v1alpha1.Foo{})).To(Succeed())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("returns the last error of handlers failing every try", func() {
+		var calls int
+		Expect(registry.Register("broken", func(context.Context, client.Client, *v1alpha1.Foo) error {
+			calls++
+			return fmt.Errorf("permanent error")
+		})).To(Succeed())
+
+		Expect(registry.Run(context.TODO(), "broken", nil, &v1alpha1.Foo{})).To(MatchError("permanent error"))
+		Expect(calls).To(Equal(DefaultBackoff.Steps))
+	})
+
+	It("has no handlers when nil", func() {
+		var nilRegistry *Registry
+		Expect(nilRegistry.GetNames()).To(BeEmpty())
+	})
+})
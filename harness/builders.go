@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harness
+
+import (
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// FooBuilder builds Foo resources for tests.
+type FooBuilder struct {
+	foo *v1alpha1.Foo
+}
+
+// BarBuilder builds Bar resources for tests.
+type BarBuilder struct {
+	bar *v1alpha1.Bar
+}
+
+// NewFoo creates and returns a FooBuilder for a Foo resource with the given name and namespace. The UID the API server
+// would assign is derived from the name, as the fake client leaves it empty.
+func NewFoo(name, namespace string) *FooBuilder {
+	return &FooBuilder{
+		foo: &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       types.UID(name + "-uid"),
+			},
+		},
+	}
+}
+
+// WithDesiredReplicas sets the desired number of replicas of the Foo resource.
+func (b *FooBuilder) WithDesiredReplicas(replicas int) *FooBuilder {
+	b.foo.Spec.DesiredReplicas = replicas
+	return b
+}
+
+// WithFinalizers adds the given finalizers to the Foo resource.
+func (b *FooBuilder) WithFinalizers(finalizers ...string) *FooBuilder {
+	b.foo.Finalizers = append(b.foo.Finalizers, finalizers...)
+	return b
+}
+
+// WithPlacement sets the member clusters the Bar replicas of the Foo resource are placed into.
+func (b *FooBuilder) WithPlacement(clusters ...string) *FooBuilder {
+	b.foo.Spec.Placement = &v1alpha1.FooPlacement{Clusters: clusters}
+	return b
+}
+
+// WithReplicas sets the list of replicas in the status of the Foo resource.
+func (b *FooBuilder) WithReplicas(replicas ...string) *FooBuilder {
+	b.foo.Status.Replicas = replicas
+	return b
+}
+
+// Build returns the Foo resource.
+func (b *FooBuilder) Build() *v1alpha1.Foo {
+	return b.foo.DeepCopy()
+}
+
+// NewBar creates and returns a BarBuilder for a Bar resource with the given name and namespace.
+func NewBar(name, namespace string) *BarBuilder {
+	return &BarBuilder{
+		bar: &v1alpha1.Bar{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+	}
+}
+
+// ForFoo associates the Bar resource with the given Foo resource, stamping the labels set by the Bar webhook at
+// admission and the FooUIDLabel set by the Foo controller on the replicas it creates.
+func (b *BarBuilder) ForFoo(foo *v1alpha1.Foo) *BarBuilder {
+	b.bar.Spec.Foo = foo.Name
+	return b.WithLabels(map[string]string{
+		v1alpha1.FooLabel:       foo.Name,
+		v1alpha1.FooUIDLabel:    string(foo.UID),
+		v1alpha1.ManagedByLabel: v1alpha1.ManagedByLabelValue,
+	})
+}
+
+// WithFinalizers adds the given finalizers to the Bar resource.
+func (b *BarBuilder) WithFinalizers(finalizers ...string) *BarBuilder {
+	b.bar.Finalizers = append(b.bar.Finalizers, finalizers...)
+	return b
+}
+
+// WithLabels adds the given labels to the Bar resource.
+func (b *BarBuilder) WithLabels(labels map[string]string) *BarBuilder {
+	if b.bar.Labels == nil {
+		b.bar.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		b.bar.Labels[key] = value
+	}
+
+	return b
+}
+
+// WithOwner sets the given Foo resource as the controller owner of the Bar resource.
+func (b *BarBuilder) WithOwner(foo *v1alpha1.Foo) *BarBuilder {
+	b.bar.OwnerReferences = append(b.bar.OwnerReferences,
+		*metav1.NewControllerRef(foo, v1alpha1.GroupVersion.WithKind("Foo")))
+	return b
+}
+
+// WithWorkload sets the workload the Bar resource materializes.
+func (b *BarBuilder) WithWorkload(workload *v1alpha1.BarWorkload) *BarBuilder {
+	b.bar.Spec.Workload = workload
+	return b
+}
+
+// Build returns the Bar resource.
+func (b *BarBuilder) Build() *v1alpha1.Bar {
+	return b.bar.DeepCopy()
+}
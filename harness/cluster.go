@@ -0,0 +1,45 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harness
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+// Cluster is a cluster.Cluster backed by the fake client of a Harness, so a Harness can stand for a member cluster of
+// a multicluster.Hub. Only its client and scheme can be used.
+type Cluster struct {
+	cluster.Cluster
+	harness *Harness
+}
+
+// AsCluster returns a Cluster backed by the fake client of the Harness.
+func (h *Harness) AsCluster() *Cluster {
+	return &Cluster{harness: h}
+}
+
+// GetClient returns the fake client of the Harness.
+func (c *Cluster) GetClient() client.Client {
+	return c.harness.Client
+}
+
+// GetScheme returns the scheme of the Harness.
+func (c *Cluster) GetScheme() *runtime.Scheme {
+	return c.harness.Scheme
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harness
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CountingClient wraps a client and counts the requests sent through it by verb, so tests can check the number of
+// API round-trips an operation takes. Status requests are counted with the "status/" prefix, e.g. "status/patch".
+type CountingClient struct {
+	client.Client
+	counts map[string]int
+	mutex  sync.Mutex
+}
+
+// countingStatusWriter wraps the status writer of a CountingClient.
+type countingStatusWriter struct {
+	client.StatusWriter
+	counter *CountingClient
+}
+
+// NewCountingClient creates and returns a CountingClient wrapping the given client.
+func NewCountingClient(cli client.Client) *CountingClient {
+	return &CountingClient{Client: cli, counts: map[string]int{}}
+}
+
+// Count returns the number of requests sent with the given verb.
+func (c *CountingClient) Count(verb string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.counts[verb]
+}
+
+// Total returns the number of requests sent with any verb.
+func (c *CountingClient) Total() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	total := 0
+	for _, count := range c.counts {
+		total += count
+	}
+
+	return total
+}
+
+// Reset sets all the counts back to zero.
+func (c *CountingClient) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.counts = map[string]int{}
+}
+
+// Get counts the call to Get of the wrapped client.
+func (c *CountingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	c.count("get")
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// List counts the call to List of the wrapped client.
+func (c *CountingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.count("list")
+	return c.Client.List(ctx, list, opts...)
+}
+
+// Create counts the call to Create of the wrapped client.
+func (c *CountingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.count("create")
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// Delete counts the call to Delete of the wrapped client.
+func (c *CountingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.count("delete")
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// Update counts the call to Update of the wrapped client.
+func (c *CountingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.count("update")
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch counts the call to Patch of the wrapped client.
+func (c *CountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.count("patch")
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// DeleteAllOf counts the call to DeleteAllOf of the wrapped client.
+func (c *CountingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	c.count("deletecollection")
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// Status returns a status writer counting the calls made through it.
+func (c *CountingClient) Status() client.StatusWriter {
+	return &countingStatusWriter{StatusWriter: c.Client.Status(), counter: c}
+}
+
+// Update counts the call to Update of the wrapped status writer.
+func (w *countingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	w.counter.count("status/update")
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+// Patch counts the call to Patch of the wrapped status writer.
+func (w *countingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	w.counter.count("status/patch")
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// count increments the number of requests sent with the given verb.
+func (c *CountingClient) count(verb string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.counts[verb]++
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harness
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/cache"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Harness holds a fake client and the objects needed to run adapter operations against it, so adapters can be tested
+// without an API server.
+type Harness struct {
+	Client   client.Client
+	Context  context.Context
+	Logger   *logr.Logger
+	Recorder *record.FakeRecorder
+	Scheme   *runtime.Scheme
+}
+
+// Result holds the outcome of running a list of operations and the resources found in the namespace afterwards.
+type Result struct {
+	Bars   []v1alpha1.Bar
+	Err    error
+	Foos   []v1alpha1.Foo
+	Result ctrl.Result
+}
+
+// New creates and returns a Harness whose fake client contains the given objects and the field indexes registered by
+// the controllers. Events recorded with the Recorder can be read from its channel.
+func New(objects ...client.Object) *Harness {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+
+	logger := ctrl.Log.WithName("harness")
+
+	return &Harness{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(objects...).
+			WithIndex(&v1alpha1.Bar{}, cache.BarFooUIDIndexField, cache.BarFooUIDIndexFunc).
+			Build(),
+		Context:  context.TODO(),
+		Logger:   &logger,
+		Recorder: record.NewFakeRecorder(100),
+		Scheme:   scheme,
+	}
+}
+
+// Reload updates the given object with its current state in the fake client. It is meant to be used on the objects
+// passed to New before handing them to an adapter, so they have the resource version set by the client.
+func (h *Harness) Reload(obj client.Object) {
+	if err := h.Client.Get(h.Context, client.ObjectKeyFromObject(obj), obj); err != nil {
+		panic(err)
+	}
+}
+
+// Run runs the given operations through controller.ReconcileHandler, the same way the controllers do, and returns
+// the reconcile result along with the Foo and Bar resources in the given namespace after running them.
+func (h *Harness) Run(namespace string, operations ...controller.Operation) *Result {
+	result := &Result{}
+	result.Result, result.Err = controller.ReconcileHandler(operations)
+
+	foos := &v1alpha1.FooList{}
+	if err := h.Client.List(h.Context, foos, client.InNamespace(namespace)); err != nil {
+		panic(err)
+	}
+	result.Foos = foos.Items
+
+	bars := &v1alpha1.BarList{}
+	if err := h.Client.List(h.Context, bars, client.InNamespace(namespace)); err != nil {
+		panic(err)
+	}
+	result.Bars = bars.Items
+
+	return result
+}
+
+// GetFoo returns the Foo resource with the given name from the Result, or nil if it doesn't exist.
+func (r *Result) GetFoo(name string) *v1alpha1.Foo {
+	for i := range r.Foos {
+		if r.Foos[i].Name == name {
+			return &r.Foos[i]
+		}
+	}
+
+	return nil
+}
+
+// GetBar returns the Bar resource with the given name from the Result, or nil if it doesn't exist.
+func (r *Result) GetBar(name string) *v1alpha1.Bar {
+	for i := range r.Bars {
+		if r.Bars[i].Name == name {
+			return &r.Bars[i]
+		}
+	}
+
+	return nil
+}
+
+// GetBarsOf returns the Bar resources from the Result owned by the given Foo resource.
+func (r *Result) GetBarsOf(foo *v1alpha1.Foo) []v1alpha1.Bar {
+	var bars []v1alpha1.Bar
+	for _, bar := range r.Bars {
+		if bar.GetFooUID() == foo.UID {
+			bars = append(bars, bar)
+		}
+	}
+
+	return bars
+}
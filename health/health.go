@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// checkTimeout is the maximum time a check waits for the API server or the cache to answer
+const checkTimeout = 5 * time.Second
+
+// CacheSyncChecker returns a healthz.Checker which fails until the informers of the given kinds of resources have
+// synced.
+func CacheSyncChecker(informers cache.Informers, objects ...client.Object) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), checkTimeout)
+		defer cancel()
+
+		for _, object := range objects {
+			// GetInformer waits for the informer to sync if the cache has been started
+			informer, err := informers.GetInformer(ctx, object)
+			if err != nil {
+				return fmt.Errorf("informer for %T not ready: %w", object, err)
+			}
+			if !informer.HasSynced() {
+				return fmt.Errorf("informer for %T has not synced yet", object)
+			}
+		}
+
+		return nil
+	}
+}
+
+// APIServerChecker returns a healthz.Checker which fails when the API server of the given cluster can't be reached.
+func APIServerChecker(config *rest.Config) (healthz.Checker, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), checkTimeout)
+		defer cancel()
+
+		err := discoveryClient.RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+		if err != nil {
+			return fmt.Errorf("API server is not reachable: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+// CertificateChecker returns a healthz.Checker which fails when the PEM certificate in the given path can't be read
+// or is not valid at the time of the check. The file is read on every check, so renewed certificates are picked up.
+func CertificateChecker(path string) healthz.Checker {
+	return func(_ *http.Request) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read certificate: %w", err)
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return fmt.Errorf("no PEM data found in %s", path)
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("invalid certificate %s: %w", path, err)
+		}
+
+		now := time.Now()
+		if now.Before(certificate.NotBefore) {
+			return fmt.Errorf("certificate %s is not valid until %s", path, certificate.NotBefore)
+		}
+		if now.After(certificate.NotAfter) {
+			return fmt.Errorf("certificate %s expired at %s", path, certificate.NotAfter)
+		}
+
+		return nil
+	}
+}
+
+// GetCertificatePath returns the path of the webhook server certificate in the given directory, defaulting to the
+// directory used by controller-runtime when empty.
+func GetCertificatePath(certDir string) string {
+	if certDir == "" {
+		certDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+
+	return filepath.Join(certDir, "tls.crt")
+}
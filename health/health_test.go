@@ -0,0 +1,155 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type (
+	// fakeInformers returns the informers in the map, keyed by object type.
+	fakeInformers struct {
+		cache.Informers
+		informers map[string]cache.Informer
+	}
+
+	// fakeInformer reports the given sync state.
+	fakeInformer struct {
+		cache.Informer
+		synced bool
+	}
+)
+
+func (f *fakeInformers) GetInformer(_ context.Context, obj client.Object) (cache.Informer, error) {
+	informer, found := f.informers[fmt.Sprintf("%T", obj)]
+	if !found {
+		return nil, fmt.Errorf("cache not started")
+	}
+
+	return informer, nil
+}
+
+func (f *fakeInformer) HasSynced() bool {
+	return f.synced
+}
+
+var _ = Describe("Health checks", func() {
+	var req *http.Request
+
+	BeforeEach(func() {
+		req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	})
+
+	Context("CacheSyncChecker", func() {
+		It("fails until all the informers have synced", func() {
+			informers := &fakeInformers{informers: map[string]cache.Informer{
+				"*v1alpha1.Foo": &fakeInformer{synced: true},
+			}}
+			checker := CacheSyncChecker(informers, &v1alpha1.Foo{}, &v1alpha1.Bar{})
+			Expect(checker(req)).To(MatchError(ContainSubstring("*v1alpha1.Bar")))
+
+			informers.informers["*v1alpha1.Bar"] = &fakeInformer{synced: false}
+			Expect(checker(req)).To(MatchError(ContainSubstring("has not synced yet")))
+
+			informers.informers["*v1alpha1.Bar"] = &fakeInformer{synced: true}
+			Expect(checker(req)).To(Succeed())
+		})
+	})
+
+	Context("APIServerChecker", func() {
+		It("fails when the API server doesn't answer", func() {
+			healthy := true
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if !healthy {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write([]byte(`{"major": "1", "minor": "26"}`))
+			}))
+			DeferCleanup(server.Close)
+
+			checker, err := APIServerChecker(&rest.Config{Host: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checker(req)).To(Succeed())
+
+			healthy = false
+			Expect(checker(req)).To(MatchError(ContainSubstring("API server is not reachable")))
+		})
+	})
+
+	Context("CertificateChecker", func() {
+		// writeCertificate writes a self-signed certificate valid between the given times, returning its path.
+		writeCertificate := func(notBefore, notAfter time.Time) string {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			template := &x509.Certificate{
+				NotAfter:     notAfter,
+				NotBefore:    notBefore,
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "webhook-service"},
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).NotTo(HaveOccurred())
+
+			path := filepath.Join(GinkgoT().TempDir(), "tls.crt")
+			Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+			return path
+		}
+
+		It("succeeds for a valid certificate", func() {
+			path := writeCertificate(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+			Expect(CertificateChecker(path)(req)).To(Succeed())
+		})
+
+		It("fails for an expired certificate", func() {
+			path := writeCertificate(time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+			Expect(CertificateChecker(path)(req)).To(MatchError(ContainSubstring("expired")))
+		})
+
+		It("fails when the certificate doesn't exist", func() {
+			Expect(CertificateChecker("/nonexistent/tls.crt")(req)).To(HaveOccurred())
+		})
+
+		It("defaults to the controller-runtime certificate directory", func() {
+			Expect(GetCertificatePath("")).To(
+				Equal(filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs", "tls.crt")))
+			Expect(GetCertificatePath("/certs")).To(Equal("/certs/tls.crt"))
+		})
+	})
+})
@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Names of the controller-runtime metrics used to track the progress of the controllers.
+const (
+	queueDepthMetric     = "workqueue_depth"
+	reconcileTotalMetric = "controller_runtime_reconcile_total"
+)
+
+type (
+	// ReconcileProgressChecker detects controllers stuck with queued requests. A controller is considered stuck when
+	// its queue hasn't been empty and it hasn't finished any reconcile for longer than the timeout. The queue depth and
+	// the number of reconciles are taken from the controller-runtime metrics.
+	ReconcileProgressChecker struct {
+		clock    func() time.Time
+		elected  <-chan struct{}
+		gatherer prometheus.Gatherer
+		mutex    sync.Mutex
+		progress map[string]controllerProgress
+		timeout  time.Duration
+	}
+
+	// controllerProgress is the number of reconciles of a controller and the last time that number changed or its
+	// queue was empty.
+	controllerProgress struct {
+		reconciles float64
+		since      time.Time
+	}
+)
+
+// NewReconcileProgressChecker creates a new ReconcileProgressChecker reading the metrics from the given gatherer.
+// Controllers only run in the leader, so the checker passes until the elected channel is closed.
+func NewReconcileProgressChecker(gatherer prometheus.Gatherer, elected <-chan struct{},
+	timeout time.Duration) *ReconcileProgressChecker {
+	return &ReconcileProgressChecker{
+		clock:    time.Now,
+		elected:  elected,
+		gatherer: gatherer,
+		progress: map[string]controllerProgress{},
+		timeout:  timeout,
+	}
+}
+
+// Check is a healthz.Checker returning an error if any controller is stuck.
+func (c *ReconcileProgressChecker) Check(_ *http.Request) error {
+	select {
+	case <-c.elected:
+	default:
+		return nil
+	}
+
+	depths, reconciles, err := c.gather()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.clock()
+	for name, depth := range depths {
+		last, found := c.progress[name]
+		if !found || depth == 0 || reconciles[name] != last.reconciles {
+			c.progress[name] = controllerProgress{reconciles: reconciles[name], since: now}
+			continue
+		}
+
+		if stuckFor := now.Sub(last.since); stuckFor > c.timeout {
+			return fmt.Errorf("controller %s has %v queued requests but hasn't finished a reconcile for %s",
+				name, depth, stuckFor.Round(time.Second))
+		}
+	}
+
+	return nil
+}
+
+// gather returns the queue depth and the number of reconciles of every controller, keyed by controller name.
+func (c *ReconcileProgressChecker) gather() (map[string]float64, map[string]float64, error) {
+	families, err := c.gatherer.Gather()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	depths := map[string]float64{}
+	reconciles := map[string]float64{}
+	for _, family := range families {
+		switch family.GetName() {
+		case queueDepthMetric:
+			for _, metric := range family.GetMetric() {
+				depths[getLabel(metric, "name")] = metric.GetGauge().GetValue()
+			}
+		case reconcileTotalMetric:
+			for _, metric := range family.GetMetric() {
+				reconciles[getLabel(metric, "controller")] += metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return depths, reconciles, nil
+}
+
+// getLabel returns the value of the label with the given name or an empty string if not found.
+func getLabel(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
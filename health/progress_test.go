@@ -0,0 +1,90 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReconcileProgressChecker", func() {
+	var (
+		checker    *ReconcileProgressChecker
+		depth      *prometheus.GaugeVec
+		elected    chan struct{}
+		now        time.Time
+		reconciles *prometheus.CounterVec
+	)
+
+	BeforeEach(func() {
+		depth = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: queueDepthMetric}, []string{"name"})
+		reconciles = prometheus.NewCounterVec(prometheus.CounterOpts{Name: reconcileTotalMetric},
+			[]string{"controller", "result"})
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(depth, reconciles)
+
+		elected = make(chan struct{})
+		now = time.Now()
+		checker = NewReconcileProgressChecker(registry, elected, time.Minute)
+		checker.clock = func() time.Time {
+			return now
+		}
+	})
+
+	It("passes while the replica is not the leader", func() {
+		depth.WithLabelValues("foo").Set(5)
+		Expect(checker.Check(nil)).To(Succeed())
+
+		now = now.Add(time.Hour)
+		Expect(checker.Check(nil)).To(Succeed())
+	})
+
+	Context("when the replica is the leader", func() {
+		BeforeEach(func() {
+			close(elected)
+		})
+
+		It("fails when a controller has queued requests and doesn't reconcile", func() {
+			depth.WithLabelValues("foo").Set(3)
+			Expect(checker.Check(nil)).To(Succeed())
+
+			now = now.Add(2 * time.Minute)
+			Expect(checker.Check(nil)).To(MatchError(ContainSubstring("controller foo has 3 queued requests")))
+		})
+
+		It("passes while the controllers keep reconciling", func() {
+			depth.WithLabelValues("foo").Set(3)
+			Expect(checker.Check(nil)).To(Succeed())
+
+			now = now.Add(2 * time.Minute)
+			reconciles.WithLabelValues("foo", "success").Inc()
+			Expect(checker.Check(nil)).To(Succeed())
+		})
+
+		It("passes when the queues are empty", func() {
+			depth.WithLabelValues("foo").Set(0)
+			Expect(checker.Check(nil)).To(Succeed())
+
+			now = now.Add(time.Hour)
+			Expect(checker.Check(nil)).To(Succeed())
+		})
+	})
+})
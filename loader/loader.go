@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/cache"
 	toolkit "github.com/konflux-ci/operator-toolkit/loader"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -11,6 +12,9 @@ import (
 type ObjectLoader interface {
 	GetBars(ctx context.Context, cli client.Client, foo *v1alpha1.Foo) ([]v1alpha1.Bar, error)
 	GetFoo(ctx context.Context, cli client.Client, name, namespace string) (*v1alpha1.Foo, error)
+	GetFooQuotas(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.FooQuota, error)
+	GetFoos(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Foo, error)
+	GetNamespaceBars(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Bar, error)
 }
 
 type loader struct{}
@@ -19,13 +23,15 @@ func NewLoader() ObjectLoader {
 	return &loader{}
 }
 
-// GetBars loads the list of Bar resources associated with the Foo resource passed as a parameter.
+// GetBars loads the list of Bar resources owned by the Foo resource passed as a parameter, selecting them by the UID
+// of their owner, so Bar resources whose labels or spec were changed afterwards are still found while those of other
+// Foo resources never are.
 func (l *loader) GetBars(ctx context.Context, cli client.Client, foo *v1alpha1.Foo) ([]v1alpha1.Bar, error) {
 	bars := &v1alpha1.BarList{}
 
 	err := cli.List(ctx, bars,
 		client.InNamespace(foo.Namespace),
-		client.MatchingFields{"spec.foo": foo.Name})
+		client.MatchingFields{cache.BarFooUIDIndexField: string(foo.UID)})
 	if err != nil {
 		return nil, err
 	}
@@ -38,3 +44,39 @@ func (l *loader) GetFoo(ctx context.Context, cli client.Client, name, namespace
 	foo := &v1alpha1.Foo{}
 	return foo, toolkit.GetObject(name, namespace, cli, ctx, foo)
 }
+
+// GetFooQuotas returns the list of FooQuota resources in the given namespace.
+func (l *loader) GetFooQuotas(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.FooQuota, error) {
+	quotas := &v1alpha1.FooQuotaList{}
+
+	err := cli.List(ctx, quotas, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	return quotas.Items, nil
+}
+
+// GetFoos returns the list of Foo resources in the given namespace.
+func (l *loader) GetFoos(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Foo, error) {
+	foos := &v1alpha1.FooList{}
+
+	err := cli.List(ctx, foos, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	return foos.Items, nil
+}
+
+// GetNamespaceBars returns the list of Bar resources in the given namespace, no matter which Foo they belong to.
+func (l *loader) GetNamespaceBars(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Bar, error) {
+	bars := &v1alpha1.BarList{}
+
+	err := cli.List(ctx, bars, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	return bars.Items, nil
+}
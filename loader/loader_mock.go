@@ -2,39 +2,303 @@ package loader
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
-	toolkit "github.com/konflux-ci/operator-toolkit/loader"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// Names of the ObjectLoader methods, used to record and assert the calls received by a MockLoader.
 const (
-	FooContextKey  toolkit.ContextKey = iota
-	BarsContextKey toolkit.ContextKey = iota
+	GetBarsMethod          = "GetBars"
+	GetFooMethod           = "GetFoo"
+	GetFooQuotasMethod     = "GetFooQuotas"
+	GetFoosMethod          = "GetFoos"
+	GetNamespaceBarsMethod = "GetNamespaceBars"
 )
 
-type mockLoader struct {
-	loader ObjectLoader
+type (
+	// ArgumentMatcher is a function that can be passed to MockExpectation.WithArgs to match an argument with custom
+	// logic instead of comparing it with the received one.
+	ArgumentMatcher func(arg any) bool
+
+	// MockCall is a call received by a MockLoader. The arguments don't include the context nor the client.
+	MockCall struct {
+		Args   []any
+		Method string
+	}
+
+	// MockExpectation is a response to be returned by a MockLoader method when receiving a matching call.
+	MockExpectation[T any] struct {
+		args   []any
+		err    error
+		mock   *MockLoader
+		name   string
+		result T
+		times  int
+	}
+
+	// MockLoader is an ObjectLoader returning queued responses. Responses are returned in the same order they were
+	// queued and every response is only returned once unless specified otherwise. Calls without a matching response
+	// fail, unless the MockLoader was set to fall through to a real loader.
+	MockLoader struct {
+		calls        []MockCall
+		expectations map[string][]mockExpectation
+		loader       ObjectLoader
+		mutex        sync.Mutex
+	}
+
+	// mockExpectation is the untyped interface of MockExpectation, so expectations for different methods can be stored
+	// together.
+	mockExpectation interface {
+		consume() (any, error)
+		exhausted() bool
+		matches(args []any) bool
+		pending() bool
+	}
+)
+
+// AnyArgument is an ArgumentMatcher matching any argument.
+var AnyArgument ArgumentMatcher = func(any) bool {
+	return true
+}
+
+// NewMockLoader creates and returns a MockLoader without any queued response.
+func NewMockLoader() *MockLoader {
+	return &MockLoader{
+		expectations: map[string][]mockExpectation{},
+	}
+}
+
+// WithFallThrough makes the MockLoader call a real loader when no queued response matches the received call.
+func (m *MockLoader) WithFallThrough() *MockLoader {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.loader = NewLoader()
+	return m
+}
+
+// OnGetBars queues a new response for GetBars. The only argument that can be matched is the Foo resource.
+func (m *MockLoader) OnGetBars() *MockExpectation[[]v1alpha1.Bar] {
+	return newMockExpectation[[]v1alpha1.Bar](m, GetBarsMethod)
+}
+
+// OnGetFoo queues a new response for GetFoo. The arguments that can be matched are the name and the namespace.
+func (m *MockLoader) OnGetFoo() *MockExpectation[*v1alpha1.Foo] {
+	return newMockExpectation[*v1alpha1.Foo](m, GetFooMethod)
+}
+
+// OnGetFooQuotas queues a new response for GetFooQuotas. The only argument that can be matched is the namespace.
+func (m *MockLoader) OnGetFooQuotas() *MockExpectation[[]v1alpha1.FooQuota] {
+	return newMockExpectation[[]v1alpha1.FooQuota](m, GetFooQuotasMethod)
+}
+
+// OnGetFoos queues a new response for GetFoos. The only argument that can be matched is the namespace.
+func (m *MockLoader) OnGetFoos() *MockExpectation[[]v1alpha1.Foo] {
+	return newMockExpectation[[]v1alpha1.Foo](m, GetFoosMethod)
+}
+
+// OnGetNamespaceBars queues a new response for GetNamespaceBars. The only argument that can be matched is the namespace.
+func (m *MockLoader) OnGetNamespaceBars() *MockExpectation[[]v1alpha1.Bar] {
+	return newMockExpectation[[]v1alpha1.Bar](m, GetNamespaceBarsMethod)
+}
+
+// Calls returns all the calls received by the MockLoader in the order they were received.
+func (m *MockLoader) Calls() []MockCall {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]MockCall{}, m.calls...)
+}
+
+// CallsTo returns the calls received by the given method in the order they were received.
+func (m *MockLoader) CallsTo(method string) []MockCall {
+	var calls []MockCall
+	for _, call := range m.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// AssertCalled returns an error if the given method wasn't called the given number of times.
+func (m *MockLoader) AssertCalled(method string, times int) error {
+	if calls := len(m.CallsTo(method)); calls != times {
+		return fmt.Errorf("expected %s to be called %d times, but it was called %d times", method, times, calls)
+	}
+
+	return nil
+}
+
+// AssertExpectationsMet returns an error if any of the queued responses hasn't been returned yet. Responses set to be
+// returned always are not taken into account.
+func (m *MockLoader) AssertExpectationsMet() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for method, expectations := range m.expectations {
+		for _, expectation := range expectations {
+			if expectation.pending() {
+				return fmt.Errorf("not all the responses queued for %s were returned", method)
+			}
+		}
+	}
+
+	return nil
 }
 
-func NewMockLoader() ObjectLoader {
-	return &mockLoader{
-		loader: NewLoader(),
+// GetBars returns the first queued response matching the call.
+func (m *MockLoader) GetBars(ctx context.Context, cli client.Client, foo *v1alpha1.Foo) ([]v1alpha1.Bar, error) {
+	return mockedCall(m, GetBarsMethod, []any{foo}, func(loader ObjectLoader) ([]v1alpha1.Bar, error) {
+		return loader.GetBars(ctx, cli, foo)
+	})
+}
+
+// GetFoo returns the first queued response matching the call.
+func (m *MockLoader) GetFoo(ctx context.Context, cli client.Client, name, namespace string) (*v1alpha1.Foo, error) {
+	return mockedCall(m, GetFooMethod, []any{name, namespace}, func(loader ObjectLoader) (*v1alpha1.Foo, error) {
+		return loader.GetFoo(ctx, cli, name, namespace)
+	})
+}
+
+// GetFooQuotas returns the first queued response matching the call.
+func (m *MockLoader) GetFooQuotas(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.FooQuota, error) {
+	return mockedCall(m, GetFooQuotasMethod, []any{namespace}, func(loader ObjectLoader) ([]v1alpha1.FooQuota, error) {
+		return loader.GetFooQuotas(ctx, cli, namespace)
+	})
+}
+
+// GetFoos returns the first queued response matching the call.
+func (m *MockLoader) GetFoos(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Foo, error) {
+	return mockedCall(m, GetFoosMethod, []any{namespace}, func(loader ObjectLoader) ([]v1alpha1.Foo, error) {
+		return loader.GetFoos(ctx, cli, namespace)
+	})
+}
+
+// GetNamespaceBars returns the first queued response matching the call.
+func (m *MockLoader) GetNamespaceBars(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Bar, error) {
+	return mockedCall(m, GetNamespaceBarsMethod, []any{namespace}, func(loader ObjectLoader) ([]v1alpha1.Bar, error) {
+		return loader.GetNamespaceBars(ctx, cli, namespace)
+	})
+}
+
+// WithArgs sets the arguments a call has to receive to get this response. Arguments are compared using
+// reflect.DeepEqual unless an ArgumentMatcher is passed.
+func (e *MockExpectation[T]) WithArgs(args ...any) *MockExpectation[T] {
+	e.args = args
+	return e
+}
+
+// Times sets the number of times this response will be returned.
+func (e *MockExpectation[T]) Times(times int) *MockExpectation[T] {
+	e.times = times
+	return e
+}
+
+// Always sets this response to be returned for every matching call.
+func (e *MockExpectation[T]) Always() *MockExpectation[T] {
+	e.times = -1
+	return e
+}
+
+// Return sets the resource and error of this response and queues it, returning the MockLoader so other responses can
+// be chained.
+func (e *MockExpectation[T]) Return(result T, err error) *MockLoader {
+	e.result = result
+	e.err = err
+
+	e.mock.mutex.Lock()
+	defer e.mock.mutex.Unlock()
+	e.mock.expectations[e.method()] = append(e.mock.expectations[e.method()], e)
+
+	return e.mock
+}
+
+// consume returns the resource and error of this response, decreasing the number of times it can be returned.
+func (e *MockExpectation[T]) consume() (any, error) {
+	if e.times > 0 {
+		e.times--
+	}
+
+	return e.result, e.err
+}
+
+// exhausted returns true if this response can't be returned anymore.
+func (e *MockExpectation[T]) exhausted() bool {
+	return e.times == 0
+}
+
+// matches returns true if the arguments passed as a parameter match the arguments expected by this response.
+func (e *MockExpectation[T]) matches(args []any) bool {
+	if e.args == nil {
+		return true
 	}
+
+	if len(e.args) != len(args) {
+		return false
+	}
+
+	for i, expected := range e.args {
+		if matcher, ok := expected.(ArgumentMatcher); ok {
+			if !matcher(args[i]) {
+				return false
+			}
+		} else if !reflect.DeepEqual(expected, args[i]) {
+			return false
+		}
+	}
+
+	return true
 }
 
-// GetBars returns the resource and error passed as values of the context.
-func (l *mockLoader) GetBars(ctx context.Context, cli client.Client, foo *v1alpha1.Foo) ([]v1alpha1.Bar, error) {
-	if ctx.Value(BarsContextKey) == nil {
-		return l.loader.GetBars(ctx, cli, foo)
+// method returns the name of the method this response was queued for.
+func (e *MockExpectation[T]) method() string {
+	return e.name
+}
+
+// pending returns true if this response still has to be returned a given number of times.
+func (e *MockExpectation[T]) pending() bool {
+	return e.times > 0
+}
+
+// newMockExpectation creates a new MockExpectation for the given method to be returned once.
+func newMockExpectation[T any](mock *MockLoader, method string) *MockExpectation[T] {
+	return &MockExpectation[T]{
+		mock:  mock,
+		name:  method,
+		times: 1,
 	}
-	return toolkit.GetMockedResourceAndErrorFromContext(ctx, BarsContextKey, []v1alpha1.Bar{})
 }
 
-// GetFoo returns the resource and error passed as values of the context.
-func (l *mockLoader) GetFoo(ctx context.Context, cli client.Client, name, namespace string) (*v1alpha1.Foo, error) {
-	if ctx.Value(FooContextKey) == nil {
-		return l.loader.GetFoo(ctx, cli, name, namespace)
+// mockedCall records the call and returns the first queued response matching it. If none matches, the call falls
+// through to the real loader if enabled or fails otherwise.
+func mockedCall[T any](m *MockLoader, method string, args []any, fallThrough func(ObjectLoader) (T, error)) (T, error) {
+	m.mutex.Lock()
+	m.calls = append(m.calls, MockCall{Args: args, Method: method})
+
+	for _, expectation := range m.expectations[method] {
+		if expectation.exhausted() || !expectation.matches(args) {
+			continue
+		}
+
+		result, err := expectation.consume()
+		m.mutex.Unlock()
+
+		return result.(T), err
 	}
-	return toolkit.GetMockedResourceAndErrorFromContext(ctx, FooContextKey, &v1alpha1.Foo{})
+
+	loader := m.loader
+	m.mutex.Unlock()
+
+	if loader == nil {
+		var empty T
+		return empty, fmt.Errorf("unexpected call to %s with arguments %v", method, args)
+	}
+
+	return fallThrough(loader)
 }
@@ -0,0 +1,126 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"fmt"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mock loader", func() {
+	var (
+		foo  *v1alpha1.Foo
+		mock *MockLoader
+	)
+
+	BeforeEach(func() {
+		foo = &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{Name: "mock-foo", Namespace: "default"},
+		}
+		mock = NewMockLoader()
+	})
+
+	It("returns the queued responses in order", func() {
+		mock.
+			OnGetNamespaceBars().Return([]v1alpha1.Bar{{}}, nil).
+			OnGetNamespaceBars().Return(nil, fmt.Errorf("list error"))
+
+		bars, err := mock.GetNamespaceBars(ctx, k8sClient, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bars).To(HaveLen(1))
+
+		bars, err = mock.GetNamespaceBars(ctx, k8sClient, "default")
+		Expect(err).To(MatchError("list error"))
+		Expect(bars).To(BeNil())
+
+		Expect(mock.AssertExpectationsMet()).To(Succeed())
+	})
+
+	It("returns a response the given number of times", func() {
+		mock.OnGetFoos().Times(2).Return([]v1alpha1.Foo{*foo}, nil)
+
+		for i := 0; i < 2; i++ {
+			foos, err := mock.GetFoos(ctx, k8sClient, "default")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(foos).To(HaveLen(1))
+		}
+
+		_, err := mock.GetFoos(ctx, k8sClient, "default")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns responses set to always be returned on every call", func() {
+		mock.OnGetFooQuotas().Always().Return([]v1alpha1.FooQuota{}, nil)
+
+		for i := 0; i < 3; i++ {
+			_, err := mock.GetFooQuotas(ctx, k8sClient, "default")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(mock.AssertExpectationsMet()).To(Succeed())
+	})
+
+	It("only returns responses matching the arguments", func() {
+		mock.
+			OnGetFoo().WithArgs("other-foo", "default").Return(nil, fmt.Errorf("not found")).
+			OnGetFoo().WithArgs(foo.Name, AnyArgument).Return(foo, nil)
+
+		loadedFoo, err := mock.GetFoo(ctx, k8sClient, foo.Name, "other-namespace")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loadedFoo).To(Equal(foo))
+
+		Expect(mock.AssertExpectationsMet()).To(HaveOccurred())
+	})
+
+	It("supports custom argument matchers", func() {
+		mock.OnGetBars().WithArgs(ArgumentMatcher(func(arg any) bool {
+			return arg.(*v1alpha1.Foo).Name == foo.Name
+		})).Return([]v1alpha1.Bar{}, nil)
+
+		_, err := mock.GetBars(ctx, k8sClient, &v1alpha1.Foo{})
+		Expect(err).To(HaveOccurred())
+
+		_, err = mock.GetBars(ctx, k8sClient, foo)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("records the calls received", func() {
+		_, _ = mock.GetFoo(ctx, k8sClient, foo.Name, foo.Namespace)
+		_, _ = mock.GetFoos(ctx, k8sClient, foo.Namespace)
+
+		Expect(mock.Calls()).To(Equal([]MockCall{
+			{Args: []any{foo.Name, foo.Namespace}, Method: GetFooMethod},
+			{Args: []any{foo.Namespace}, Method: GetFoosMethod},
+		}))
+		Expect(mock.CallsTo(GetFoosMethod)).To(HaveLen(1))
+		Expect(mock.AssertCalled(GetFooMethod, 1)).To(Succeed())
+		Expect(mock.AssertCalled(GetBarsMethod, 1)).To(HaveOccurred())
+	})
+
+	It("falls through to the real loader when enabled", func() {
+		Expect(k8sClient.Create(ctx, foo)).To(Succeed())
+		mock.WithFallThrough()
+
+		loadedFoo, err := mock.GetFoo(ctx, k8sClient, foo.Name, foo.Namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loadedFoo.Name).To(Equal(foo.Name))
+	})
+})
@@ -0,0 +1,181 @@
+package loader
+
+import (
+	"context"
+	"sync"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Invalidator is implemented by loaders caching the objects they return, so the cached objects can be discarded
+// after writing to the cluster.
+type Invalidator interface {
+	Invalidate()
+}
+
+// ClusterClient is a client of a member cluster. The snapshot loader memoizes the objects it returns apart from the
+// ones returned by any other client, which are assumed to be the client of the cluster the operator runs in.
+type ClusterClient interface {
+	client.Client
+
+	// ClusterName returns the name of the member cluster
+	ClusterName() string
+}
+
+type clusterClient struct {
+	client.Client
+	name string
+}
+
+// NewClusterClient returns a ClusterClient for the member cluster with the given name, reading from and writing to it
+// with the given client.
+func NewClusterClient(name string, cli client.Client) ClusterClient {
+	return &clusterClient{Client: cli, name: name}
+}
+
+// ClusterName returns the name of the member cluster.
+func (c *clusterClient) ClusterName() string {
+	return c.name
+}
+
+// snapshotKey identifies a loader call. The cluster is part of the key as the same loader can be used to get objects
+// from different clusters.
+type snapshotKey struct {
+	cluster   string
+	method    string
+	name      string
+	namespace string
+}
+
+type snapshotLoader struct {
+	entries map[snapshotKey]any
+	loader  ObjectLoader
+	mutex   sync.Mutex
+}
+
+// NewSnapshotLoader returns an ObjectLoader memoizing the objects returned by the loader passed as a parameter, so
+// every object is only loaded once. It is meant to be scoped to a single reconcile, and it has to be invalidated
+// after writing objects that could be returned by it. Copies of the memoized objects are returned, so they can be
+// modified without affecting the following calls.
+func NewSnapshotLoader(loader ObjectLoader) ObjectLoader {
+	return &snapshotLoader{
+		entries: map[snapshotKey]any{},
+		loader:  loader,
+	}
+}
+
+// Invalidate discards the objects cached by the given loader, if any.
+func Invalidate(loader ObjectLoader) {
+	if invalidator, ok := loader.(Invalidator); ok {
+		invalidator.Invalidate()
+	}
+}
+
+// Invalidate discards all the memoized objects, so they get loaded again on the next call.
+func (l *snapshotLoader) Invalidate() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries = map[snapshotKey]any{}
+}
+
+// GetBars returns a copy of the memoized list of Bar resources associated with the Foo resource passed as a parameter,
+// loading them if needed.
+func (l *snapshotLoader) GetBars(ctx context.Context, cli client.Client, foo *v1alpha1.Foo) ([]v1alpha1.Bar, error) {
+	key := snapshotKey{cluster: getClusterName(cli), method: "GetBars", name: foo.Name, namespace: foo.Namespace}
+	bars, err := memoize(l, key, func() ([]v1alpha1.Bar, error) {
+		return l.loader.GetBars(ctx, cli, foo)
+	})
+
+	return deepCopyItems(bars), err
+}
+
+// GetFoo returns a copy of the memoized Foo resource with the given name and namespace, loading it if needed.
+func (l *snapshotLoader) GetFoo(ctx context.Context, cli client.Client, name, namespace string) (*v1alpha1.Foo, error) {
+	key := snapshotKey{cluster: getClusterName(cli), method: "GetFoo", name: name, namespace: namespace}
+	foo, err := memoize(l, key, func() (*v1alpha1.Foo, error) {
+		return l.loader.GetFoo(ctx, cli, name, namespace)
+	})
+	if err != nil {
+		return foo, err
+	}
+
+	return foo.DeepCopy(), nil
+}
+
+// GetFooQuotas returns a copy of the memoized list of FooQuota resources in the given namespace, loading them if
+// needed.
+func (l *snapshotLoader) GetFooQuotas(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.FooQuota, error) {
+	key := snapshotKey{cluster: getClusterName(cli), method: "GetFooQuotas", namespace: namespace}
+	quotas, err := memoize(l, key, func() ([]v1alpha1.FooQuota, error) {
+		return l.loader.GetFooQuotas(ctx, cli, namespace)
+	})
+
+	return deepCopyItems(quotas), err
+}
+
+// GetFoos returns a copy of the memoized list of Foo resources in the given namespace, loading them if needed.
+func (l *snapshotLoader) GetFoos(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Foo, error) {
+	key := snapshotKey{cluster: getClusterName(cli), method: "GetFoos", namespace: namespace}
+	foos, err := memoize(l, key, func() ([]v1alpha1.Foo, error) {
+		return l.loader.GetFoos(ctx, cli, namespace)
+	})
+
+	return deepCopyItems(foos), err
+}
+
+// GetNamespaceBars returns a copy of the memoized list of Bar resources in the given namespace, loading them if needed.
+func (l *snapshotLoader) GetNamespaceBars(ctx context.Context, cli client.Client, namespace string) ([]v1alpha1.Bar, error) {
+	key := snapshotKey{cluster: getClusterName(cli), method: "GetNamespaceBars", namespace: namespace}
+	bars, err := memoize(l, key, func() ([]v1alpha1.Bar, error) {
+		return l.loader.GetNamespaceBars(ctx, cli, namespace)
+	})
+
+	return deepCopyItems(bars), err
+}
+
+// getClusterName returns the name of the member cluster of the given client, or an empty string if it isn't a
+// ClusterClient.
+func getClusterName(cli client.Client) string {
+	if clusterClient, ok := cli.(ClusterClient); ok {
+		return clusterClient.ClusterName()
+	}
+
+	return ""
+}
+
+// deepCopyItems returns a deep copy of the given list of objects.
+func deepCopyItems[T any, PT interface {
+	*T
+	DeepCopyInto(*T)
+}](items []T) []T {
+	if items == nil {
+		return nil
+	}
+
+	copies := make([]T, len(items))
+	for i := range items {
+		PT(&items[i]).DeepCopyInto(&copies[i])
+	}
+
+	return copies
+}
+
+// memoize returns the object stored in the snapshot for the given key. If not there, the object is loaded using the
+// load function and stored in the snapshot. Errors are never memoized, so failed calls are retried.
+func memoize[T any](l *snapshotLoader, key snapshotKey, load func() (T, error)) (T, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if entry, found := l.entries[key]; found {
+		return entry.(T), nil
+	}
+
+	object, err := load()
+	if err == nil {
+		l.entries[key] = object
+	}
+
+	return object, err
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot loader", Ordered, func() {
+	var (
+		foo      *v1alpha1.Foo
+		inner    *MockLoader
+		snapshot ObjectLoader
+	)
+
+	createBar := func(name string) {
+		Expect(k8sClient.Create(ctx, &v1alpha1.Bar{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{v1alpha1.FooLabel: foo.Name, v1alpha1.FooUIDLabel: string(foo.UID)},
+			},
+			Spec: v1alpha1.BarSpec{Foo: foo.Name},
+		})).To(Succeed())
+	}
+
+	BeforeAll(func() {
+		foo = &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapshot-foo", Namespace: "default", UID: "snapshot-foo-uid"},
+		}
+		Expect(k8sClient.Create(ctx, foo)).To(Succeed())
+		createBar("snapshot-bar-1")
+	})
+
+	BeforeEach(func() {
+		inner = NewMockLoader().WithFallThrough()
+		snapshot = NewSnapshotLoader(inner)
+	})
+
+	It("only lists the Bars once", func() {
+		for i := 0; i < 3; i++ {
+			bars, err := snapshot.GetBars(ctx, k8sClient, foo)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bars).To(HaveLen(1))
+		}
+		Expect(inner.AssertCalled(GetBarsMethod, 1)).To(Succeed())
+	})
+
+	It("lists the Bars again after being invalidated", func() {
+		_, err := snapshot.GetBars(ctx, k8sClient, foo)
+		Expect(err).NotTo(HaveOccurred())
+
+		createBar("snapshot-bar-2")
+		Invalidate(snapshot)
+
+		bars, err := snapshot.GetBars(ctx, k8sClient, foo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bars).To(HaveLen(2))
+		Expect(inner.AssertCalled(GetBarsMethod, 2)).To(Succeed())
+	})
+
+	It("returns copies of the memoized Bars", func() {
+		bars, err := snapshot.GetBars(ctx, k8sClient, foo)
+		Expect(err).NotTo(HaveOccurred())
+		bars[0].Labels[v1alpha1.FooLabel] = "other"
+
+		bars, err = snapshot.GetBars(ctx, k8sClient, foo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bars[0].Labels).To(HaveKeyWithValue(v1alpha1.FooLabel, foo.Name))
+	})
+
+	It("memoizes the objects of every cluster apart", func() {
+		member := NewClusterClient("member", k8sClient)
+		for _, cli := range []client.Client{k8sClient, member, NewClusterClient("member", k8sClient)} {
+			_, err := snapshot.GetBars(ctx, cli, foo)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(inner.AssertCalled(GetBarsMethod, 2)).To(Succeed())
+	})
+
+	It("returns copies of the memoized Foo", func() {
+		loadedFoo, err := snapshot.GetFoo(ctx, k8sClient, foo.Name, foo.Namespace)
+		Expect(err).NotTo(HaveOccurred())
+		loadedFoo.Spec.DesiredReplicas = 10
+
+		loadedFoo, err = snapshot.GetFoo(ctx, k8sClient, foo.Name, foo.Namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loadedFoo.Spec.DesiredReplicas).To(BeZero())
+	})
+
+	It("doesn't memoize errors", func() {
+		_, err := snapshot.GetFoo(ctx, k8sClient, "missing-foo", "default")
+		Expect(err).To(HaveOccurred())
+
+		Expect(k8sClient.Create(ctx, &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing-foo", Namespace: "default"},
+		})).To(Succeed())
+
+		_, err = snapshot.GetFoo(ctx, k8sClient, "missing-foo", "default")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("is a no-op to invalidate loaders not caching objects", func() {
+		Expect(func() { Invalidate(NewLoader()) }).NotTo(Panic())
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loader", Ordered, func() {
+	var fooA, fooB, fooC *v1alpha1.Foo
+
+	newFoo := func(name string) *v1alpha1.Foo {
+		foo := &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ownership", UID: types.UID(name + "-uid")},
+		}
+		Expect(k8sClient.Create(ctx, foo)).To(Succeed())
+		return foo
+	}
+
+	BeforeAll(func() {
+		fooA, fooB, fooC = newFoo("foo-a"), newFoo("foo-b"), newFoo("foo-c")
+
+		// Controlled by foo-a while its label points to foo-b and its spec to foo-c
+		Expect(k8sClient.Create(ctx, &v1alpha1.Bar{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "controlled-bar",
+				Namespace: "ownership",
+				Labels:    map[string]string{v1alpha1.FooLabel: fooB.Name},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(fooA, v1alpha1.GroupVersion.WithKind("Foo")),
+				},
+			},
+			Spec: v1alpha1.BarSpec{Foo: fooC.Name},
+		})).To(Succeed())
+
+		// Labeled with the UID of foo-b, which takes precedence over its controller reference to foo-a
+		Expect(k8sClient.Create(ctx, &v1alpha1.Bar{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "labeled-bar",
+				Namespace: "ownership",
+				Labels:    map[string]string{v1alpha1.FooLabel: fooA.Name, v1alpha1.FooUIDLabel: string(fooB.UID)},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(fooA, v1alpha1.GroupVersion.WithKind("Foo")),
+				},
+			},
+			Spec: v1alpha1.BarSpec{Foo: fooA.Name},
+		})).To(Succeed())
+	})
+
+	getBarNames := func(foo *v1alpha1.Foo) []string {
+		bars, err := NewLoader().GetBars(ctx, k8sClient, foo)
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, bar := range bars {
+			names = append(names, bar.Name)
+		}
+		return names
+	}
+
+	It("returns every Bar to a single Foo", func() {
+		Expect(getBarNames(fooA)).To(ConsistOf("controlled-bar"))
+		Expect(getBarNames(fooB)).To(ConsistOf("labeled-bar"))
+		Expect(getBarNames(fooC)).To(BeEmpty())
+	})
+})
@@ -17,11 +17,21 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
 
 	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1/webhooks"
 	"github.com/konflux-ci/operator-toolkit-example/controllers"
+	"github.com/konflux-ci/operator-toolkit-example/debug"
+	"github.com/konflux-ci/operator-toolkit-example/finalizers"
+	"github.com/konflux-ci/operator-toolkit-example/health"
+	"github.com/konflux-ci/operator-toolkit-example/metrics"
+	"github.com/konflux-ci/operator-toolkit-example/multicluster"
+	"github.com/konflux-ci/operator-toolkit-example/operatorconfig"
+	"github.com/konflux-ci/operator-toolkit-example/sharding"
+	"github.com/konflux-ci/operator-toolkit-example/tracing"
 	"github.com/konflux-ci/operator-toolkit/controller"
 	"github.com/konflux-ci/operator-toolkit/webhook"
 
@@ -33,8 +43,11 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	appstudiov1alpha1 "github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
 	//+kubebuilder:scaffold:imports
@@ -53,14 +66,38 @@ func init() {
 }
 
 func main() {
+	var configFile string
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+	var memberClustersNamespace string
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var enabledControllers string
+	var enabledWebhooks string
+	defaults := operatorconfig.DefaultConfig()
+	flag.StringVar(&configFile, "config", "",
+		"The path of the operator configuration file. Flags set explicitly override the values in the file.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", defaults.Metrics.BindAddress,
+		"The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", defaults.Health.ProbeBindAddress,
+		"The address the probe endpoint binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", defaults.LeaderElection.Enabled,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&memberClustersNamespace, "member-clusters-namespace", "",
+		"The namespace containing the kubeconfig Secrets of the member clusters. "+
+			"Setting it enables the multi-cluster mode, in which Bar resources can be placed into member clusters.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP/HTTP collector the reconcile traces are exported to. Tracing is disabled if not set.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Export the traces to the OTLP collector without TLS.")
+	flag.StringVar(&enabledControllers, "controllers", strings.Join(defaults.EnabledControllers, ","),
+		"A comma-separated list of the controllers to run. '*' enables all the controllers, 'foo' enables the "+
+			"controller named foo and '-foo' disables it. Available controllers: "+
+			strings.Join(operatorconfig.ControllerNames, ", ")+".")
+	flag.StringVar(&enabledWebhooks, "webhooks", strings.Join(defaults.EnabledWebhooks, ","),
+		"A comma-separated list of the webhooks to serve, using the same syntax as --controllers. "+
+			"Available webhooks: "+strings.Join(operatorconfig.WebhookNames, ", ")+".")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -69,54 +106,200 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "c2d03d47.redhat.com",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
+	// Load the operator configuration, overriding it with the flags set explicitly
+	config := defaults
+	if configFile != "" {
+		var err error
+		config, err = operatorconfig.Load(configFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load the operator configuration")
+			os.Exit(1)
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "metrics-bind-address":
+			config.Metrics.BindAddress = metricsAddr
+		case "health-probe-bind-address":
+			config.Health.ProbeBindAddress = probeAddr
+		case "leader-elect":
+			config.LeaderElection.Enabled = enableLeaderElection
+		case "controllers":
+			config.EnabledControllers = operatorconfig.ParseComponents(enabledControllers)
+		case "webhooks":
+			config.EnabledWebhooks = operatorconfig.ParseComponents(enabledWebhooks)
+		}
 	})
+	if os.Getenv("ENABLE_WEBHOOKS") == "false" {
+		config.SetFeatureEnabled(operatorconfig.WebhooksFeature, false)
+	}
+	if err := config.Validate(); err != nil {
+		setupLog.Error(err, "invalid operator configuration")
+		os.Exit(1)
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+
+	// Resolve the namespace selector into the list of watched namespaces
+	if config.NamespaceSelector != nil {
+		reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create the client to resolve the namespace selector")
+			os.Exit(1)
+		}
+
+		err = config.ResolveNamespaces(context.Background(), reader)
+		if err != nil {
+			setupLog.Error(err, "unable to resolve the namespace selector")
+			os.Exit(1)
+		}
+	}
+	if len(config.Namespaces) > 0 {
+		setupLog.Info("watching a restricted set of namespaces", "namespaces", config.Namespaces)
+	}
+
+	// Report the enabled controllers and webhooks
+	setupLog.Info("enabled components", "controllers", config.GetEnabledControllers(),
+		"webhooks", config.GetEnabledWebhooks())
+	metrics.RecordEnabledComponents(metrics.ControllerComponent, operatorconfig.ControllerNames,
+		config.GetEnabledControllers())
+	metrics.RecordEnabledComponents(metrics.WebhookComponent, operatorconfig.WebhookNames, config.GetEnabledWebhooks())
+
+	// Register the finalizer handlers run by the Foo controller when a Foo is deleted
+	err := finalizers.Register(metrics.FinalizerHandlerName, metrics.DeleteFooMetrics)
+	if err != nil {
+		setupLog.Error(err, "unable to register the finalizer handlers")
+		os.Exit(1)
+	}
+
+	// Setup tracing
+	if otlpEndpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), otlpEndpoint, otlpInsecure)
+		if err != nil {
+			setupLog.Error(err, "unable to setup tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			_ = shutdown(context.Background())
+		}()
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, config.GetManagerOptions(scheme))
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// Setup the member clusters in multi-cluster mode
+	var hub cluster.Cluster
+	if memberClustersNamespace != "" {
+		multiClusterHub := multicluster.NewHub(mgr)
+		err = multicluster.LoadMembers(context.Background(), mgr, multiClusterHub, memberClustersNamespace,
+			config.SetClusterNamespaces)
+		if err != nil {
+			setupLog.Error(err, "unable to load member clusters")
+			os.Exit(1)
+		}
+		setupLog.Info("multi-cluster mode enabled", "members", multiClusterHub.GetMemberNames())
+		hub = multiClusterHub
+	}
+
+	// Setup the sharded mode, in which the Foo resources are distributed among the replicas
+	if config.Sharding.Enabled {
+		identity, err := os.Hostname()
+		if err != nil {
+			setupLog.Error(err, "unable to get the identity of the replica")
+			os.Exit(1)
+		}
+
+		namespace := config.Sharding.LeaseNamespace
+		if namespace == "" {
+			namespace, err = sharding.GetInClusterNamespace()
+			if err != nil {
+				setupLog.Error(err, "unable to get the namespace of the shard leases")
+				os.Exit(1)
+			}
+		}
+
+		sharder := sharding.NewSharder(mgr.GetClient(), mgr.GetAPIReader(), sharding.Options{
+			Buckets:       config.Sharding.Buckets,
+			Identity:      identity,
+			LeaseDuration: config.Sharding.LeaseDuration.Duration,
+			Name:          config.LeaderElection.ID,
+			Namespace:     namespace,
+			RenewPeriod:   config.Sharding.RenewPeriod.Duration,
+		})
+		if err := mgr.Add(sharder); err != nil {
+			setupLog.Error(err, "unable to setup the sharder")
+			os.Exit(1)
+		}
+		controllers.SetSharder(sharder)
+		setupLog.Info("sharded mode enabled", "identity", identity, "buckets", config.Sharding.Buckets)
+	}
+
 	// Setup controllers
-	err = controller.SetupControllers(mgr, nil, controllers.EnabledControllers...)
+	controllers.Configure(config)
+	err = controller.SetupControllers(mgr, hub, controllers.EnabledControllers(config)...)
 	if err != nil {
 		setupLog.Error(err, "unable to setup controllers")
 		os.Exit(1)
 	}
 
 	// Setup webhooks
-	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		err = webhook.SetupWebhooks(mgr, webhooks.EnabledWebhooks...)
-		if err != nil {
-			setupLog.Error(err, "unable to setup webhooks")
+	webhooks.Configure(config)
+	err = webhook.SetupWebhooks(mgr, webhooks.EnabledWebhooks(config)...)
+	if err != nil {
+		setupLog.Error(err, "unable to setup webhooks")
+		os.Exit(1)
+	}
+
+	// Setup the endpoint requesting the reconcile of a Foo or Bar resource
+	if config.IsFeatureEnabled(operatorconfig.ReconcileTriggerFeature) {
+		handler := debug.NewReconcileHandler(mgr.GetClient(), ctrl.Log.WithName("debug"))
+		if err := mgr.AddMetricsExtraHandler(debug.ReconcilePath, handler); err != nil {
+			setupLog.Error(err, "unable to set up the reconcile trigger")
 			os.Exit(1)
 		}
 	}
 
+	// Setup health checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+	if timeout := config.Health.ReconcileProgressTimeout.Duration; timeout > 0 {
+		progressChecker := health.NewReconcileProgressChecker(crmetrics.Registry, mgr.Elected(), timeout)
+		if err := mgr.AddHealthzCheck("reconcile-progress", progressChecker.Check); err != nil {
+			setupLog.Error(err, "unable to set up reconcile progress check")
+			os.Exit(1)
+		}
+	}
+	apiServerChecker, err := health.APIServerChecker(restConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create API server check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("apiserver", apiServerChecker); err != nil {
+		setupLog.Error(err, "unable to set up API server check")
 		os.Exit(1)
 	}
+	err = mgr.AddReadyzCheck("informers",
+		health.CacheSyncChecker(mgr.GetCache(), &appstudiov1alpha1.Foo{}, &appstudiov1alpha1.Bar{}))
+	if err != nil {
+		setupLog.Error(err, "unable to set up informers check")
+		os.Exit(1)
+	}
+	if len(config.GetEnabledWebhooks()) > 0 {
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook check")
+			os.Exit(1)
+		}
+		certificateChecker := health.CertificateChecker(health.GetCertificatePath(config.Webhook.CertDir))
+		if err := mgr.AddReadyzCheck("webhook-certificate", certificateChecker); err != nil {
+			setupLog.Error(err, "unable to set up webhook certificate check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Kinds of the components reported in the EnabledComponents metric.
+const (
+	ControllerComponent = "controller"
+	WebhookComponent    = "webhook"
+)
+
+// EnabledComponents reports whether every controller and webhook is enabled (1) or disabled (0).
+var EnabledComponents = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "operator_toolkit_example_enabled_components",
+		Help: "Whether each controller and webhook of the operator is enabled (1) or disabled (0)",
+	},
+	[]string{"component", "name"},
+)
+
+// FinalizerHandlerName is the name of the finalizer handler deleting the metrics of the Foo resources being deleted.
+const FinalizerHandlerName = "metrics"
+
+// FooReplicas reports the number of Bar replicas of every Foo resource, in all the clusters they are placed into.
+var FooReplicas = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "operator_toolkit_example_foo_replicas",
+		Help: "Number of Bar replicas of each Foo resource, in all the clusters they are placed into",
+	},
+	[]string{"namespace", "name"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(EnabledComponents, FooReplicas)
+}
+
+// DeleteFooMetrics is a finalizer handler deleting the metrics of the given Foo resource, so they are not reported
+// anymore once it is gone.
+func DeleteFooMetrics(_ context.Context, _ client.Client, foo *v1alpha1.Foo) error {
+	FooReplicas.DeleteLabelValues(foo.Namespace, foo.Name)
+	return nil
+}
+
+// RecordEnabledComponents sets the EnabledComponents metric of every component of the given kind with one of the
+// given names, marking as enabled the ones also contained in the enabled slice.
+func RecordEnabledComponents(component string, names, enabled []string) {
+	for _, name := range names {
+		value := 0.0
+		for _, enabledName := range enabled {
+			if enabledName == name {
+				value = 1
+				break
+			}
+		}
+		EnabledComponents.WithLabelValues(component, name).Set(value)
+	}
+}
+
+// RecordFooReplicas sets the FooReplicas metric of the given Foo resource.
+func RecordFooReplicas(foo *v1alpha1.Foo, replicas int) {
+	FooReplicas.WithLabelValues(foo.Namespace, foo.Name).Set(float64(replicas))
+}
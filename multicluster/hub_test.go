@@ -0,0 +1,181 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster_test
+
+import (
+	"context"
+
+	"github.com/konflux-ci/operator-toolkit-example/multicluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// memberCluster is a cluster.Cluster standing for a member cluster, as the Hub doesn't use it.
+type memberCluster struct {
+	cluster.Cluster
+}
+
+var _ = Describe("Hub", func() {
+	var hub *multicluster.Hub
+
+	BeforeEach(func() {
+		hub = multicluster.NewHub(nil)
+	})
+
+	It("calls the member listeners with the existing and the added member clusters", func() {
+		Expect(hub.AddMember("member-1", &memberCluster{})).To(Succeed())
+
+		var watched []string
+		Expect(hub.WatchMembers(func(_ context.Context, name string, member cluster.Cluster) error {
+			watched = append(watched, name)
+			return nil
+		})).To(Succeed())
+		Expect(watched).To(Equal([]string{"member-1"}))
+
+		Expect(hub.AddMember("member-2", &memberCluster{})).To(Succeed())
+		Expect(watched).To(Equal([]string{"member-1", "member-2"}))
+		Expect(hub.GetMemberNames()).To(Equal([]string{"member-1", "member-2"}))
+	})
+
+	It("removes member clusters", func() {
+		Expect(hub.AddMember("member-1", &memberCluster{})).To(Succeed())
+		hub.RemoveMember("member-1")
+
+		_, found := hub.GetMember("member-1")
+		Expect(found).To(BeFalse())
+		Expect(hub.GetMemberNames()).To(BeEmpty())
+	})
+
+	Context("when watching the events of the member clusters", func() {
+		var (
+			events map[cluster.Cluster]chan event.GenericEvent
+			queue  workqueue.RateLimitingInterface
+			stop   chan struct{}
+		)
+
+		newMember := func() cluster.Cluster {
+			member := &memberCluster{}
+			events[member] = make(chan event.GenericEvent, 1)
+			return member
+		}
+
+		send := func(member cluster.Cluster, name string) {
+			events[member] <- event.GenericEvent{Object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}}}
+		}
+
+		// Every member cluster is watched the way the controllers do, enqueuing its events in the same queue
+		BeforeEach(func() {
+			events = map[cluster.Cluster]chan event.GenericEvent{}
+			queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			stop = make(chan struct{})
+			Expect(hub.WatchMembers(func(ctx context.Context, _ string, member cluster.Cluster) error {
+				src := &source.Channel{Source: events[member]}
+				Expect(src.InjectStopChannel(stop)).To(Succeed())
+				return src.Start(context.TODO(), &handler.EnqueueRequestForObject{}, queue,
+					multicluster.MemberPredicate(ctx))
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			close(stop)
+			queue.ShutDown()
+		})
+
+		It("stops delivering the events of the replaced member clusters", func() {
+			previous := newMember()
+			Expect(hub.AddMember("member-1", previous)).To(Succeed())
+			current := newMember()
+			Expect(hub.AddMember("member-1", current)).To(Succeed())
+
+			send(previous, "previous")
+			Consistently(queue.Len).Should(BeZero())
+
+			send(current, "current")
+			Eventually(queue.Len).Should(Equal(1))
+			item, _ := queue.Get()
+			Expect(item.(reconcile.Request).Name).To(Equal("current"))
+		})
+
+		It("stops delivering the events of the removed member clusters", func() {
+			member := newMember()
+			Expect(hub.AddMember("member-1", member)).To(Succeed())
+			hub.RemoveMember("member-1")
+
+			send(member, "removed")
+			Consistently(queue.Len).Should(BeZero())
+		})
+	})
+})
+
+var _ = Describe("MemberLoader", func() {
+	const namespace = "operator"
+
+	var (
+		hub    *multicluster.Hub
+		reader client.Client
+	)
+
+	newSecret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{multicluster.MemberClusterLabel: "true"},
+			},
+			Data: data,
+		}
+	}
+
+	BeforeEach(func() {
+		hub = multicluster.NewHub(nil)
+		reader = fake.NewClientBuilder().Build()
+	})
+
+	It("skips the Secrets without a valid kubeconfig and reports them", func() {
+		Expect(reader.Create(context.TODO(), newSecret("missing", nil))).To(Succeed())
+		Expect(reader.Create(context.TODO(), newSecret("invalid", map[string][]byte{
+			multicluster.KubeconfigSecretKey: []byte("not a kubeconfig"),
+		}))).To(Succeed())
+		loader := multicluster.NewMemberLoader(reader, hub, namespace, ctrl.Log)
+
+		err := loader.Sync(context.TODO())
+		Expect(err).To(MatchError(ContainSubstring("unable to load 2 member clusters")))
+		Expect(hub.GetMemberNames()).To(BeEmpty())
+	})
+
+	It("ignores the Secrets without the member cluster label", func() {
+		secret := newSecret("unlabeled", nil)
+		secret.Labels = nil
+		Expect(reader.Create(context.TODO(), secret)).To(Succeed())
+		loader := multicluster.NewMemberLoader(reader, hub, namespace, ctrl.Log)
+
+		Expect(loader.Sync(context.TODO())).To(Succeed())
+		Expect(hub.GetMemberNames()).To(BeEmpty())
+	})
+})
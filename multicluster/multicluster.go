@@ -0,0 +1,361 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// MemberClusterLabel is the label identifying the Secrets that contain the kubeconfig of a member cluster
+	MemberClusterLabel = "appstudio.redhat.com/member-cluster"
+
+	// KubeconfigSecretKey is the key of the member cluster Secrets containing the kubeconfig
+	KubeconfigSecretKey = "kubeconfig"
+
+	// MemberSyncPeriod is how often the member cluster Secrets are listed again once the manager is started
+	MemberSyncPeriod = 30 * time.Second
+)
+
+// MemberListener is a function called with every member cluster added to a Hub. The given context is cancelled once
+// the member cluster is removed from the Hub or replaced, so anything started for it can be stopped.
+type MemberListener func(ctx context.Context, name string, member cluster.Cluster) error
+
+// Hub represents the cluster Foo resources live in. It implements cluster.Cluster, so it can be passed to the
+// controllers when registering them, and keeps track of the member clusters Bar resources can be placed into.
+type Hub struct {
+	cluster.Cluster
+
+	listeners []MemberListener
+	mutex     sync.RWMutex
+	members   map[string]*hubMember
+}
+
+// hubMember is a member cluster of a Hub along with the context passed to the member listeners with it.
+type hubMember struct {
+	cancel  context.CancelFunc
+	cluster cluster.Cluster
+	ctx     context.Context
+}
+
+// NewHub creates and returns a Hub for the given cluster without any member cluster.
+func NewHub(hub cluster.Cluster) *Hub {
+	return &Hub{
+		Cluster: hub,
+		members: map[string]*hubMember{},
+	}
+}
+
+// AddMember adds a member cluster to the Hub, replacing any other member cluster with the same name, and calls the
+// member listeners with it. The context the listeners were called with for the replaced member cluster is cancelled.
+// The errors of the listeners are returned.
+func (h *Hub) AddMember(name string, member cluster.Cluster) error {
+	added := &hubMember{cluster: member}
+	added.ctx, added.cancel = context.WithCancel(context.Background())
+
+	h.mutex.Lock()
+	if previous, found := h.members[name]; found {
+		previous.cancel()
+	}
+	h.members[name] = added
+	listeners := h.listeners
+	h.mutex.Unlock()
+
+	for _, listener := range listeners {
+		err := listener(added.ctx, name, member)
+		if err != nil {
+			return fmt.Errorf("unable to add member cluster %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveMember removes the member cluster with the given name from the Hub and cancels the context the member
+// listeners were called with for it.
+func (h *Hub) RemoveMember(name string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if member, found := h.members[name]; found {
+		member.cancel()
+		delete(h.members, name)
+	}
+}
+
+// WatchMembers calls the given listener with every member cluster in the Hub and with every member cluster added
+// later, so controllers can watch the resources in member clusters loaded after they are registered.
+func (h *Hub) WatchMembers(listener MemberListener) error {
+	h.mutex.Lock()
+	h.listeners = append(h.listeners, listener)
+	members := make(map[string]*hubMember, len(h.members))
+	for name, member := range h.members {
+		members[name] = member
+	}
+	h.mutex.Unlock()
+
+	for _, name := range sortedNames(members) {
+		err := listener(members[name].ctx, name, members[name].cluster)
+		if err != nil {
+			return fmt.Errorf("unable to watch member cluster %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetMember returns the member cluster with the given name and whether it was found or not.
+func (h *Hub) GetMember(name string) (cluster.Cluster, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	member, found := h.members[name]
+	if !found {
+		return nil, false
+	}
+
+	return member.cluster, true
+}
+
+// GetMemberNames returns the sorted list of names of the member clusters in the Hub.
+func (h *Hub) GetMemberNames() []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return sortedNames(h.members)
+}
+
+// LoadMembers creates a member cluster for every Secret in the given namespace labeled with MemberClusterLabel. The
+// name of the Secret is used as the name of the member cluster. The given options are applied when creating every
+// member cluster. A MemberLoader is added to the manager, so the member clusters are started along with it and kept
+// in sync with the Secrets afterwards.
+func LoadMembers(ctx context.Context, mgr manager.Manager, hub *Hub, namespace string, opts ...cluster.Option) error {
+	loader := NewMemberLoader(mgr.GetAPIReader(), hub, namespace, mgr.GetLogger().WithName("member-loader"),
+		append([]cluster.Option{func(options *cluster.Options) {
+			options.Scheme = mgr.GetScheme()
+		}}, opts...)...)
+
+	err := loader.Sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	return mgr.Add(loader)
+}
+
+// MemberLoader keeps the member clusters of a Hub in sync with the Secrets labeled with MemberClusterLabel in a
+// namespace. Member clusters are added when their Secret is created, replaced when it changes and removed when it is
+// deleted or unlabeled. It implements manager.Runnable, starting the member clusters and listing the Secrets again
+// every MemberSyncPeriod once started.
+type MemberLoader struct {
+	ctx       context.Context
+	hub       *Hub
+	log       logr.Logger
+	members   map[string]*loadedMember
+	mutex     sync.Mutex
+	namespace string
+	options   []cluster.Option
+	reader    client.Reader
+}
+
+// NewMemberLoader creates and returns a MemberLoader adding the member clusters of the Secrets in the given namespace
+// to the given Hub. The Secrets are listed with the given reader and the given options are applied when creating every
+// member cluster.
+func NewMemberLoader(reader client.Reader, hub *Hub, namespace string, log logr.Logger,
+	opts ...cluster.Option) *MemberLoader {
+	return &MemberLoader{
+		hub:       hub,
+		log:       log,
+		members:   map[string]*loadedMember{},
+		namespace: namespace,
+		options:   opts,
+		reader:    reader,
+	}
+}
+
+// loadedMember is a member cluster created from a Secret.
+type loadedMember struct {
+	cancel          context.CancelFunc
+	cluster         cluster.Cluster
+	resourceVersion string
+}
+
+// Start starts the member clusters loaded so far and keeps them in sync with the Secrets until the context is done.
+// The member clusters are stopped along with it.
+func (l *MemberLoader) Start(ctx context.Context) error {
+	l.mutex.Lock()
+	l.ctx = ctx
+	for name, member := range l.members {
+		l.start(name, member)
+	}
+	l.mutex.Unlock()
+
+	ticker := time.NewTicker(MemberSyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := l.Sync(ctx)
+			if err != nil {
+				l.log.Error(err, "unable to sync the member clusters")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so the member clusters are loaded in every replica,
+// as the ones which aren't the leader reconcile in the sharded mode.
+func (l *MemberLoader) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync adds, replaces and removes the member clusters of the Hub to match the labeled Secrets. Secrets which can't be
+// loaded are skipped, so the member clusters loaded from their previous version are kept, and the errors returned.
+func (l *MemberLoader) Sync(ctx context.Context) error {
+	secrets := &corev1.SecretList{}
+	err := l.reader.List(ctx, secrets,
+		client.InNamespace(l.namespace),
+		client.HasLabels{MemberClusterLabel})
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var errs []error
+	found := map[string]bool{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		found[secret.Name] = true
+
+		if member, loaded := l.members[secret.Name]; loaded && member.resourceVersion == secret.ResourceVersion {
+			continue
+		}
+
+		err := l.load(secret)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for name, member := range l.members {
+		if !found[name] {
+			l.remove(name, member)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to load %d member clusters: %v", len(errs), errs)
+	}
+
+	return nil
+}
+
+// load creates the member cluster of the given Secret and adds it to the Hub, replacing the one loaded from a
+// previous version of the Secret, and starts it.
+func (l *MemberLoader) load(secret *corev1.Secret) error {
+	kubeconfig, found := secret.Data[KubeconfigSecretKey]
+	if !found {
+		return fmt.Errorf("secret %s/%s doesn't contain the %s key", secret.Namespace, secret.Name, KubeconfigSecretKey)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("invalid kubeconfig in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	memberCluster, err := cluster.New(config, l.options...)
+	if err != nil {
+		return err
+	}
+
+	if previous, loaded := l.members[secret.Name]; loaded {
+		l.remove(secret.Name, previous)
+	}
+
+	// The member cluster is added to the Hub before being started, as the listeners might need to index its cache
+	member := &loadedMember{cluster: memberCluster, resourceVersion: secret.ResourceVersion}
+	l.members[secret.Name] = member
+	err = l.hub.AddMember(secret.Name, memberCluster)
+	l.start(secret.Name, member)
+	l.log.Info("Member cluster loaded", "Cluster", secret.Name)
+
+	return err
+}
+
+// remove stops the given member cluster and removes it from the Hub.
+func (l *MemberLoader) remove(name string, member *loadedMember) {
+	if member.cancel != nil {
+		member.cancel()
+	}
+	delete(l.members, name)
+	l.hub.RemoveMember(name)
+	l.log.Info("Member cluster removed", "Cluster", name)
+}
+
+// start starts the given member cluster unless the loader isn't started yet, in which case it is started along with
+// the loader.
+func (l *MemberLoader) start(name string, member *loadedMember) {
+	if l.ctx == nil || member.cancel != nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, member.cancel = context.WithCancel(l.ctx)
+	go func() {
+		err := member.cluster.Start(ctx)
+		if err != nil {
+			l.log.Error(err, "member cluster stopped", "Cluster", name)
+		}
+	}()
+}
+
+// MemberPredicate returns a predicate filtering out every event once the given context is done. Passing it along with
+// the context a MemberListener is called with stops the watches of a member cluster once it is removed or replaced,
+// even if its cache is still running.
+func MemberPredicate(ctx context.Context) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(client.Object) bool {
+		return ctx.Err() == nil
+	})
+}
+
+// sortedNames returns the sorted names of the given member clusters.
+func sortedNames(members map[string]*hubMember) []string {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster_test
+
+import (
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multi-cluster mode", Ordered, func() {
+	var foo *v1alpha1.Foo
+
+	getMemberBars := func() []v1alpha1.Bar {
+		bars := &v1alpha1.BarList{}
+		Expect(memberClient.List(ctx, bars, client.InNamespace("default"),
+			client.MatchingLabels{v1alpha1.FooLabel: foo.Name})).To(Succeed())
+		return bars.Items
+	}
+
+	BeforeAll(func() {
+		requireEnvtest()
+
+		foo = &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "placed-foo",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.FooSpec{
+				DesiredReplicas: 2,
+				Placement: &v1alpha1.FooPlacement{
+					Clusters: []string{memberName},
+				},
+			},
+		}
+		Expect(hubClient.Create(ctx, foo)).To(Succeed())
+	})
+
+	It("loads the member clusters from the labeled Secrets", func() {
+		Expect(hub.GetMemberNames()).To(Equal([]string{memberName}))
+	})
+
+	It("creates the Bar replicas in the member cluster", func() {
+		Eventually(getMemberBars).Should(HaveLen(2))
+
+		hubBars := &v1alpha1.BarList{}
+		Expect(hubClient.List(ctx, hubBars, client.InNamespace("default"))).To(Succeed())
+		Expect(hubBars.Items).To(BeEmpty())
+	})
+
+	It("aggregates the member cluster replicas into the Foo status", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(hubClient.Get(ctx, client.ObjectKeyFromObject(foo), foo)).To(Succeed())
+			g.Expect(foo.Status.Clusters).To(HaveLen(1))
+			g.Expect(foo.Status.Clusters[0].Name).To(Equal(memberName))
+			g.Expect(foo.Status.Clusters[0].Replicas).To(HaveLen(2))
+		}).Should(Succeed())
+	})
+
+	It("recreates the Bar replicas deleted in the member cluster", func() {
+		bars := getMemberBars()
+		Expect(memberClient.Delete(ctx, &bars[0])).To(Succeed())
+
+		Eventually(func() []string {
+			var names []string
+			for _, bar := range getMemberBars() {
+				names = append(names, bar.Name)
+			}
+			return names
+		}).Should(SatisfyAll(HaveLen(2), Not(ContainElement(bars[0].Name))))
+	})
+
+	It("deletes the Bar replicas in the member cluster when the Foo is deleted", func() {
+		Expect(hubClient.Delete(ctx, foo)).To(Succeed())
+
+		Eventually(getMemberBars).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,148 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konflux-ci/operator-toolkit-example/controllers/foo"
+	"github.com/konflux-ci/operator-toolkit-example/multicluster"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	appstudiov1alpha1 "github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	//+kubebuilder:scaffold:imports
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+const memberName = "member"
+
+var (
+	hubClient    client.Client
+	hubEnv       *envtest.Environment
+	memberClient client.Client
+	memberEnv    *envtest.Environment
+	hub          *multicluster.Hub
+	ctx          context.Context
+	cancel       context.CancelFunc
+)
+
+func TestMultiCluster(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "MultiCluster Suite")
+}
+
+// requireEnvtest skips the current spec when the test environments aren't running.
+func requireEnvtest() {
+	if hubClient == nil {
+		Skip("KUBEBUILDER_ASSETS is not set, run the tests through 'make test'")
+	}
+}
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	// The specs of the Hub and the member loader don't need an API server, so they run even without envtest
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		return
+	}
+
+	ctx, cancel = context.WithCancel(context.TODO())
+
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(appstudiov1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	By("bootstrapping the hub and member test environments")
+	hubEnv = &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	memberEnv = &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+
+	hubCfg, err := hubEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	memberCfg, err := memberEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+
+	hubClient, err = client.New(hubCfg, client.Options{Scheme: scheme})
+	Expect(err).NotTo(HaveOccurred())
+	memberClient, err = client.New(memberCfg, client.Options{Scheme: scheme})
+	Expect(err).NotTo(HaveOccurred())
+
+	By("storing the member cluster kubeconfig in the hub")
+	memberUser, err := memberEnv.AddUser(envtest.User{Name: "hub", Groups: []string{"system:masters"}}, nil)
+	Expect(err).NotTo(HaveOccurred())
+	kubeconfig, err := memberUser.KubeConfig()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(hubClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memberName,
+			Namespace: "default",
+			Labels:    map[string]string{multicluster.MemberClusterLabel: "true"},
+		},
+		Data: map[string][]byte{multicluster.KubeconfigSecretKey: kubeconfig},
+	})).To(Succeed())
+
+	k8sManager, err := ctrl.NewManager(hubCfg, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0", // disables metrics
+		LeaderElection:     false,
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	hub = multicluster.NewHub(k8sManager)
+	Expect(multicluster.LoadMembers(ctx, k8sManager, hub, "default")).To(Succeed())
+	Expect(controller.SetupControllers(k8sManager, hub, &foo.Controller{})).To(Succeed())
+
+	go func() {
+		defer GinkgoRecover()
+		Expect(k8sManager.Start(ctx)).To(Succeed())
+	}()
+})
+
+var _ = AfterSuite(func() {
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	By("tearing down the test environments")
+	Expect(hubEnv.Stop()).To(Succeed())
+	Expect(memberEnv.Stop()).To(Succeed())
+})
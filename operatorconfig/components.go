@@ -0,0 +1,112 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// AllComponents enables every controller or webhook not disabled explicitly when added to EnabledControllers or
+// EnabledWebhooks.
+const AllComponents = "*"
+
+// IsControllerEnabled returns true if the controller with the given name is enabled. The FooQuota controller is
+// always disabled when the FooQuotas feature is.
+func (c *OperatorConfig) IsControllerEnabled(name string) bool {
+	if name == FooQuotaController && !c.IsFeatureEnabled(FooQuotasFeature) {
+		return false
+	}
+
+	return isEnabled(c.EnabledControllers, name)
+}
+
+// IsWebhookEnabled returns true if the webhook with the given name is enabled. All the webhooks are disabled when
+// the Webhooks feature is.
+func (c *OperatorConfig) IsWebhookEnabled(name string) bool {
+	return c.IsFeatureEnabled(WebhooksFeature) && isEnabled(c.EnabledWebhooks, name)
+}
+
+// GetEnabledControllers returns the sorted names of the enabled controllers.
+func (c *OperatorConfig) GetEnabledControllers() []string {
+	return filterEnabled(ControllerNames, c.IsControllerEnabled)
+}
+
+// GetEnabledWebhooks returns the sorted names of the enabled webhooks.
+func (c *OperatorConfig) GetEnabledWebhooks() []string {
+	return filterEnabled(WebhookNames, c.IsWebhookEnabled)
+}
+
+// ParseComponents parses a comma-separated list of controllers or webhooks as passed in the command line flags.
+// Empty items are ignored, so an empty string enables nothing.
+func ParseComponents(value string) []string {
+	components := []string{}
+	for _, component := range strings.Split(value, ",") {
+		if component = strings.TrimSpace(component); component != "" {
+			components = append(components, component)
+		}
+	}
+
+	return components
+}
+
+// filterEnabled returns the names in the given slice for which the given function returns true.
+func filterEnabled(names []string, enabledFunc func(string) bool) []string {
+	enabled := []string{}
+	for _, name := range names {
+		if enabledFunc(name) {
+			enabled = append(enabled, name)
+		}
+	}
+
+	return enabled
+}
+
+// isEnabled returns true if the given name is enabled in the given list of components. Names enabled or disabled
+// explicitly take precedence over AllComponents.
+func isEnabled(components []string, name string) bool {
+	if contains(components, name) {
+		return true
+	}
+	if contains(components, "-"+name) {
+		return false
+	}
+
+	return contains(components, AllComponents)
+}
+
+// validateComponents returns an error for every item of the given list of components which is not AllComponents nor
+// one of the given names, optionally prefixed with "-", and for every name both enabled and disabled.
+func validateComponents(path *field.Path, components, names []string) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, component := range components {
+		if component == AllComponents {
+			continue
+		}
+
+		name := strings.TrimPrefix(component, "-")
+		if !contains(names, name) {
+			errs = append(errs, field.NotSupported(path.Index(i), name, names))
+		} else if name == component && contains(components, "-"+name) {
+			errs = append(errs, field.Invalid(path.Index(i), name, "can't be both enabled and disabled"))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Components", func() {
+	var config *OperatorConfig
+
+	BeforeEach(func() {
+		config = DefaultConfig()
+	})
+
+	It("enables all the controllers and webhooks by default", func() {
+		Expect(config.GetEnabledControllers()).To(Equal(ControllerNames))
+		Expect(config.GetEnabledWebhooks()).To(Equal(WebhookNames))
+	})
+
+	It("only enables the components listed", func() {
+		config.EnabledControllers = ParseComponents("foo, -bar")
+		config.EnabledWebhooks = ParseComponents("")
+
+		Expect(config.GetEnabledControllers()).To(Equal([]string{FooController}))
+		Expect(config.GetEnabledWebhooks()).To(BeEmpty())
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("disables the components listed with a dash when all are enabled", func() {
+		config.EnabledControllers = ParseComponents("*,-bar")
+
+		Expect(config.IsControllerEnabled(BarController)).To(BeFalse())
+		Expect(config.GetEnabledControllers()).To(Equal([]string{FooController, FooQuotaController}))
+	})
+
+	It("disables the components of the disabled features", func() {
+		config.SetFeatureEnabled(FooQuotasFeature, false)
+		config.SetFeatureEnabled(WebhooksFeature, false)
+
+		Expect(config.GetEnabledControllers()).To(Equal([]string{BarController, FooController}))
+		Expect(config.GetEnabledWebhooks()).To(BeEmpty())
+	})
+
+	It("rejects unknown and contradictory components", func() {
+		config.EnabledControllers = []string{"foo", "-foo"}
+		config.EnabledWebhooks = []string{"*", "-baz"}
+
+		err := config.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("enabledControllers[0]: Invalid value"))
+		Expect(err.Error()).To(ContainSubstring("enabledWebhooks[1]: Unsupported value: \"baz\""))
+	})
+})
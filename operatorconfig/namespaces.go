@@ -0,0 +1,90 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// NamespaceFilter tells whether a namespace is watched by the operator. The zero value matches every namespace.
+type NamespaceFilter struct {
+	namespaces map[string]bool
+}
+
+// GetNamespaceFilter returns a NamespaceFilter matching the watched namespaces.
+func (c *OperatorConfig) GetNamespaceFilter() NamespaceFilter {
+	if len(c.Namespaces) == 0 {
+		return NamespaceFilter{}
+	}
+
+	namespaces := make(map[string]bool, len(c.Namespaces))
+	for _, namespace := range c.Namespaces {
+		namespaces[namespace] = true
+	}
+
+	return NamespaceFilter{namespaces: namespaces}
+}
+
+// ResolveNamespaces replaces the namespace selector with the list of namespaces matching it. It does nothing if no
+// namespace selector is set. An error is returned if no namespace matches the selector, as the operator would watch
+// nothing otherwise.
+func (c *OperatorConfig) ResolveNamespaces(ctx context.Context, reader client.Reader) error {
+	if c.NamespaceSelector == nil {
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(c.NamespaceSelector)
+	if err != nil {
+		return err
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	err = reader.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return err
+	}
+
+	if len(namespaces.Items) == 0 {
+		return fmt.Errorf("no namespace matches the namespace selector %s", selector)
+	}
+
+	c.Namespaces = make([]string, len(namespaces.Items))
+	for i, namespace := range namespaces.Items {
+		c.Namespaces[i] = namespace.Name
+	}
+	c.NamespaceSelector = nil
+
+	return nil
+}
+
+// Contains returns true if the given namespace is watched.
+func (f NamespaceFilter) Contains(namespace string) bool {
+	return f.namespaces == nil || f.namespaces[namespace]
+}
+
+// Predicate returns a predicate filtering out the events of the objects in namespaces not watched.
+func (f NamespaceFilter) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return f.Contains(obj.GetNamespace())
+	})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watched namespaces", func() {
+	newNamespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	It("watches every namespace by default", func() {
+		filter := DefaultConfig().GetNamespaceFilter()
+		Expect(filter.Contains("any")).To(BeTrue())
+	})
+
+	It("only watches the configured namespaces", func() {
+		config := DefaultConfig()
+		config.Namespaces = []string{"team-a", "team-b"}
+
+		filter := config.GetNamespaceFilter()
+		Expect(filter.Contains("team-a")).To(BeTrue())
+		Expect(filter.Contains("team-c")).To(BeFalse())
+
+		predicate := filter.Predicate()
+		Expect(predicate.Create(event.CreateEvent{Object: newNamespace("", nil)})).To(BeFalse())
+		Expect(predicate.Create(event.CreateEvent{Object: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "team-b"},
+		}})).To(BeTrue())
+	})
+
+	It("resolves the namespace selector", func() {
+		reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			newNamespace("team-a", map[string]string{"tenant": "true"}),
+			newNamespace("team-b", map[string]string{"tenant": "true"}),
+			newNamespace("kube-system", nil),
+		).Build()
+
+		config := DefaultConfig()
+		config.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+		Expect(config.ResolveNamespaces(context.TODO(), reader)).To(Succeed())
+		Expect(config.Namespaces).To(ConsistOf("team-a", "team-b"))
+		Expect(config.NamespaceSelector).To(BeNil())
+	})
+
+	It("fails to resolve a namespace selector matching no namespace", func() {
+		reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+		config := DefaultConfig()
+		config.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+		Expect(config.ResolveNamespaces(context.TODO(), reader)).NotTo(Succeed())
+	})
+
+	It("rejects setting both the namespaces and the namespace selector", func() {
+		config := DefaultConfig()
+		config.Namespaces = []string{"team-a"}
+		config.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+
+		err := config.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("namespaceSelector: Forbidden"))
+	})
+})
@@ -0,0 +1,608 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the only supported version of the configuration file
+	APIVersion = "config.appstudio.redhat.com/v1alpha1"
+
+	// Kind is the kind of the configuration file
+	Kind = "OperatorConfig"
+)
+
+// Names of the controllers that can be configured.
+const (
+	BarController      = "bar"
+	FooController      = "foo"
+	FooQuotaController = "fooquota"
+)
+
+// Names of the webhooks that can be enabled.
+const (
+	BarWebhook = "bar"
+	FooWebhook = "foo"
+)
+
+// Names of the features that can be toggled.
+const (
+	// FooQuotasFeature enables the enforcement of the FooQuota resources by the webhooks and the Foo controller, and
+	// the controller reporting their usage
+	FooQuotasFeature = "FooQuotas"
+
+	// ReconcileTriggerFeature enables the endpoint of the metrics server requesting the reconcile of a resource. It
+	// is disabled unless enabled in the feature gates
+	ReconcileTriggerFeature = "ReconcileTrigger"
+
+	// WebhooksFeature enables the admission webhooks
+	WebhooksFeature = "Webhooks"
+)
+
+var (
+	// ControllerNames contains the names of all the controllers, sorted
+	ControllerNames = []string{BarController, FooController, FooQuotaController}
+
+	// WebhookNames contains the names of all the webhooks, sorted
+	WebhookNames = []string{BarWebhook, FooWebhook}
+
+	// knownOrphanPolicies is used to validate the orphan policy
+	knownOrphanPolicies = []string{string(OrphanPolicyAdopt), string(OrphanPolicyDelete), string(OrphanPolicyWait)}
+
+	// knownFeatures is used to validate the configuration file
+	knownFeatures = []string{FooQuotasFeature, ReconcileTriggerFeature, WebhooksFeature}
+
+	// optInFeatures contains the features which are disabled unless enabled in the feature gates
+	optInFeatures = []string{ReconcileTriggerFeature}
+)
+
+// OrphanPolicy defines what happens to the Bar resources whose Foo resource doesn't exist.
+type OrphanPolicy string
+
+const (
+	// OrphanPolicyAdopt keeps the orphaned Bar resources, which are adopted as soon as their Foo resource is created
+	OrphanPolicyAdopt OrphanPolicy = "Adopt"
+
+	// OrphanPolicyDelete deletes the Bar resources which are still orphaned after the grace period
+	OrphanPolicyDelete OrphanPolicy = "Delete"
+
+	// OrphanPolicyWait keeps the orphaned Bar resources, which are only adopted when reconciled again after their Foo
+	// resource is created, e.g. when updated
+	OrphanPolicyWait OrphanPolicy = "Wait"
+)
+
+// Configurable is implemented by the controllers and webhooks accepting the operator configuration.
+type Configurable interface {
+	// Configure applies the operator configuration. It is called before registering the controller or webhook.
+	Configure(config *OperatorConfig)
+}
+
+// OperatorConfig defines the configuration of the operator
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Controllers holds the configuration of every controller, keyed by controller name
+	// +optional
+	Controllers map[string]ControllerConfig `json:"controllers,omitempty"`
+
+	// EnabledControllers is the list of controllers to run. "*" enables all the controllers, "foo" enables the
+	// controller named foo and "-foo" disables it
+	// +optional
+	EnabledControllers []string `json:"enabledControllers,omitempty"`
+
+	// EnabledWebhooks is the list of webhooks to serve, using the same syntax as EnabledControllers
+	// +optional
+	EnabledWebhooks []string `json:"enabledWebhooks,omitempty"`
+
+	// FeatureGates enables or disables the features of the operator, keyed by feature name
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// FinalizationTimeout is the duration a Foo resource being deleted waits for its Bar resources to be gone before
+	// its finalizer is removed anyway. Set it to 0 to wait forever
+	// +optional
+	FinalizationTimeout metav1.Duration `json:"finalizationTimeout,omitempty"`
+
+	// FinalizerDomain is the domain of the finalizers added by the operator
+	// +optional
+	FinalizerDomain string `json:"finalizerDomain,omitempty"`
+
+	// Health holds the configuration of the health probes
+	// +optional
+	Health HealthConfig `json:"health,omitempty"`
+
+	// LeaderElection holds the configuration of the leader election
+	// +optional
+	LeaderElection LeaderElectionConfig `json:"leaderElection,omitempty"`
+
+	// MaxReplicaStatuses is the maximum number of replicas listed in the status of a Foo resource for every cluster,
+	// so the status of Foo resources with many replicas doesn't grow too large. Set it to 0 to list all the replicas
+	// +optional
+	MaxReplicaStatuses int `json:"maxReplicaStatuses,omitempty"`
+
+	// Metrics holds the configuration of the metrics endpoint
+	// +optional
+	Metrics MetricsConfig `json:"metrics,omitempty"`
+
+	// Namespaces is the list of namespaces watched by the operator. All namespaces are watched when empty
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the namespaces watched by the operator by their labels. It is resolved into the list
+	// of namespaces at startup, so namespaces labeled afterwards are only watched after restarting the operator. It
+	// can't be set along with Namespaces
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Orphans holds the configuration of the handling of orphaned Bar resources
+	// +optional
+	Orphans OrphansConfig `json:"orphans,omitempty"`
+
+	// Sharding holds the configuration of the sharded mode
+	// +optional
+	Sharding ShardingConfig `json:"sharding,omitempty"`
+
+	// Webhook holds the configuration of the webhook server
+	// +optional
+	Webhook WebhookConfig `json:"webhook,omitempty"`
+}
+
+// ControllerConfig defines the configuration of a controller
+type ControllerConfig struct {
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles the controller can run
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+}
+
+// HealthConfig defines the configuration of the health probes
+type HealthConfig struct {
+	// ProbeBindAddress is the address the probe endpoint binds to
+	// +optional
+	ProbeBindAddress string `json:"probeBindAddress,omitempty"`
+
+	// ReconcileProgressTimeout is the duration a controller can go without finishing a reconcile while having queued
+	// requests before the liveness probe fails. Set it to 0 to disable the check
+	// +optional
+	ReconcileProgressTimeout metav1.Duration `json:"reconcileProgressTimeout,omitempty"`
+}
+
+// LeaderElectionConfig defines the configuration of the leader election
+type LeaderElectionConfig struct {
+	// Enabled enables the leader election, ensuring there is only one active controller manager
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ID is the name of the resource used to hold the leader lock
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// LeaseDuration is the duration non-leader candidates wait before trying to acquire the leadership
+	// +optional
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// RenewDeadline is the duration the leader retries refreshing the leadership before giving it up
+	// +optional
+	RenewDeadline metav1.Duration `json:"renewDeadline,omitempty"`
+
+	// RetryPeriod is the duration the candidates wait between tries of actions
+	// +optional
+	RetryPeriod metav1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// MetricsConfig defines the configuration of the metrics endpoint
+type MetricsConfig struct {
+	// BindAddress is the address the metrics endpoint binds to. Set it to "0" to disable the metrics endpoint
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// OrphansConfig defines how the Bar resources whose Foo resource doesn't exist are handled
+type OrphansConfig struct {
+	// GracePeriod is the duration a Bar resource can be orphaned before being deleted with the Delete policy
+	// +optional
+	GracePeriod metav1.Duration `json:"gracePeriod,omitempty"`
+
+	// Policy is the policy applied to the orphaned Bar resources. One of Adopt, Delete or Wait
+	// +optional
+	Policy OrphanPolicy `json:"policy,omitempty"`
+}
+
+// ShardingConfig defines the configuration of the sharded mode, in which the Foo resources are distributed among all
+// the replicas of the operator instead of being reconciled by the leader
+type ShardingConfig struct {
+	// Enabled enables the sharded mode. The leader election has to be disabled in this mode
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Buckets is the number of hash buckets the Foo resources are distributed into. It has to be the same in every
+	// replica and should be several times the number of replicas, so the buckets can be spread evenly
+	// +optional
+	Buckets int `json:"buckets,omitempty"`
+
+	// LeaseNamespace is the namespace of the leases holding the buckets. The namespace of the operator is used if not
+	// set
+	// +optional
+	LeaseNamespace string `json:"leaseNamespace,omitempty"`
+
+	// LeaseDuration is the duration a replica holds its buckets without renewing them
+	// +optional
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// RenewPeriod is the duration between the renewals of the leases, which is also how often buckets are rebalanced
+	// +optional
+	RenewPeriod metav1.Duration `json:"renewPeriod,omitempty"`
+}
+
+// WebhookConfig defines the configuration of the webhook server
+type WebhookConfig struct {
+	// CertDir is the directory containing the server key and certificate. The default directory of controller-runtime
+	// is used if not set
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+
+	// Port is the port the webhook server listens on
+	// +optional
+	Port int `json:"port,omitempty"`
+}
+
+// DefaultConfig returns the configuration used when no configuration file is passed.
+func DefaultConfig() *OperatorConfig {
+	return &OperatorConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: APIVersion,
+			Kind:       Kind,
+		},
+		EnabledControllers:  []string{AllComponents},
+		EnabledWebhooks:     []string{AllComponents},
+		FinalizationTimeout: metav1.Duration{Duration: 30 * time.Minute},
+		FinalizerDomain:     "appstudio.redhat.com",
+		Health: HealthConfig{
+			ProbeBindAddress:         ":8081",
+			ReconcileProgressTimeout: metav1.Duration{Duration: 10 * time.Minute},
+		},
+		LeaderElection: LeaderElectionConfig{
+			ID:            "c2d03d47.redhat.com",
+			LeaseDuration: metav1.Duration{Duration: 15 * time.Second},
+			RenewDeadline: metav1.Duration{Duration: 10 * time.Second},
+			RetryPeriod:   metav1.Duration{Duration: 2 * time.Second},
+		},
+		MaxReplicaStatuses: 100,
+		Metrics: MetricsConfig{
+			BindAddress: ":8080",
+		},
+		Orphans: OrphansConfig{
+			GracePeriod: metav1.Duration{Duration: time.Hour},
+			Policy:      OrphanPolicyAdopt,
+		},
+		Sharding: ShardingConfig{
+			Buckets:       32,
+			LeaseDuration: metav1.Duration{Duration: 15 * time.Second},
+			RenewPeriod:   metav1.Duration{Duration: 5 * time.Second},
+		},
+		Webhook: WebhookConfig{
+			Port: 9443,
+		},
+	}
+}
+
+// Load reads the configuration file in the given path. Values not set in the file keep their default value. Unknown
+// fields are rejected, so typos don't get silently ignored.
+func Load(path string) (*OperatorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := DefaultConfig()
+	err = yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Validate returns an error listing all the invalid values in the configuration.
+func (c *OperatorConfig) Validate() error {
+	var errs field.ErrorList
+
+	if c.APIVersion != APIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{APIVersion}))
+	}
+	if c.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+
+	controllersPath := field.NewPath("controllers")
+	for _, name := range sortedKeys(c.Controllers) {
+		if !contains(ControllerNames, name) {
+			errs = append(errs, field.NotSupported(controllersPath, name, ControllerNames))
+		}
+		if c.Controllers[name].MaxConcurrentReconciles < 0 {
+			errs = append(errs, field.Invalid(controllersPath.Key(name).Child("maxConcurrentReconciles"),
+				c.Controllers[name].MaxConcurrentReconciles, "must be greater than or equal to 0"))
+		}
+	}
+
+	errs = append(errs, validateComponents(field.NewPath("enabledControllers"), c.EnabledControllers, ControllerNames)...)
+	errs = append(errs, validateComponents(field.NewPath("enabledWebhooks"), c.EnabledWebhooks, WebhookNames)...)
+
+	for _, name := range sortedKeys(c.FeatureGates) {
+		if !contains(knownFeatures, name) {
+			errs = append(errs, field.NotSupported(field.NewPath("featureGates"), name, knownFeatures))
+		}
+	}
+
+	if c.FinalizationTimeout.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("finalizationTimeout"), c.FinalizationTimeout.Duration.String(),
+			"must be greater than or equal to 0"))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(c.FinalizerDomain) {
+		errs = append(errs, field.Invalid(field.NewPath("finalizerDomain"), c.FinalizerDomain, msg))
+	}
+
+	errs = append(errs, validateAddress(field.NewPath("health", "probeBindAddress"), c.Health.ProbeBindAddress)...)
+	if c.Health.ReconcileProgressTimeout.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("health", "reconcileProgressTimeout"),
+			c.Health.ReconcileProgressTimeout.Duration.String(), "must be greater than or equal to 0"))
+	}
+	errs = append(errs, validateAddress(field.NewPath("metrics", "bindAddress"), c.Metrics.BindAddress)...)
+	if c.MaxReplicaStatuses < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxReplicaStatuses"), c.MaxReplicaStatuses,
+			"must be greater than or equal to 0"))
+	}
+	errs = append(errs, c.validateLeaderElection(field.NewPath("leaderElection"))...)
+	errs = append(errs, c.validateSharding(field.NewPath("sharding"))...)
+
+	if !contains(knownOrphanPolicies, string(c.Orphans.Policy)) {
+		errs = append(errs, field.NotSupported(field.NewPath("orphans", "policy"), c.Orphans.Policy, knownOrphanPolicies))
+	}
+	if c.Orphans.GracePeriod.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("orphans", "gracePeriod"), c.Orphans.GracePeriod.Duration.String(),
+			"must be greater than or equal to 0"))
+	}
+
+	if c.NamespaceSelector != nil {
+		if len(c.Namespaces) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("namespaceSelector"), "can't be set along with namespaces"))
+		}
+		if _, err := metav1.LabelSelectorAsSelector(c.NamespaceSelector); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("namespaceSelector"), c.NamespaceSelector, err.Error()))
+		}
+	}
+
+	for i, namespace := range c.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(field.NewPath("namespaces").Index(i), namespace, msg))
+		}
+	}
+
+	if c.Webhook.Port < 1 || c.Webhook.Port > 65535 {
+		errs = append(errs, field.Invalid(field.NewPath("webhook", "port"), c.Webhook.Port, "must be between 1 and 65535"))
+	}
+
+	return errs.ToAggregate()
+}
+
+// GetControllerConfig returns the configuration of the controller with the given name.
+func (c *OperatorConfig) GetControllerConfig(name string) ControllerConfig {
+	return c.Controllers[name]
+}
+
+// GetDrainFinalizerName returns the name of the finalizer keeping the Bar resources being deleted until they are
+// drained, which is prefixed with the finalizer domain.
+func (c *OperatorConfig) GetDrainFinalizerName() string {
+	return c.FinalizerDomain + "/drain"
+}
+
+// GetFinalizerName returns the name of the finalizer added by the operator, which is prefixed with the finalizer
+// domain.
+func (c *OperatorConfig) GetFinalizerName() string {
+	return c.FinalizerDomain + "/finalizer"
+}
+
+// IsFeatureEnabled returns true if the feature with the given name is enabled in the feature gates. Features not set
+// in the feature gates are enabled unless they are opt-in.
+func (c *OperatorConfig) IsFeatureEnabled(name string) bool {
+	enabled, found := c.FeatureGates[name]
+	if !found {
+		return !contains(optInFeatures, name)
+	}
+
+	return enabled
+}
+
+// SetFeatureEnabled enables or disables the feature with the given name.
+func (c *OperatorConfig) SetFeatureEnabled(name string, enabled bool) {
+	if c.FeatureGates == nil {
+		c.FeatureGates = map[string]bool{}
+	}
+	c.FeatureGates[name] = enabled
+}
+
+// GetManagerOptions returns the manager options matching the configuration.
+func (c *OperatorConfig) GetManagerOptions(scheme *runtime.Scheme) ctrl.Options {
+	options := ctrl.Options{
+		Scheme:                 scheme,
+		MetricsBindAddress:     c.Metrics.BindAddress,
+		Port:                   c.Webhook.Port,
+		CertDir:                c.Webhook.CertDir,
+		HealthProbeBindAddress: c.Health.ProbeBindAddress,
+		LeaderElection:         c.LeaderElection.Enabled,
+		LeaderElectionID:       c.LeaderElection.ID,
+		LeaseDuration:          &c.LeaderElection.LeaseDuration.Duration,
+		RenewDeadline:          &c.LeaderElection.RenewDeadline.Duration,
+		RetryPeriod:            &c.LeaderElection.RetryPeriod.Duration,
+		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+		// when the Manager ends. This requires the binary to immediately end when the
+		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+		// speeds up voluntary leader transitions as the new leader don't have to wait
+		// LeaseDuration time first.
+		//
+		// In the default scaffold provided, the program ends immediately after
+		// the manager stops, so would be fine to enable this option. However,
+		// if you are doing or is intended to do any operation such as perform cleanups
+		// after the manager stops then its usage might be unsafe.
+		// LeaderElectionReleaseOnCancel: true,
+	}
+
+	if len(c.Namespaces) == 1 {
+		options.Namespace = c.Namespaces[0]
+	}
+	options.NewCache = c.newCacheFunc()
+
+	return options
+}
+
+// newCacheFunc returns a function creating the cache of the manager, which only holds the Deployments and ConfigMaps
+// managed by the operator and is restricted to the watched namespaces.
+func (c *OperatorConfig) newCacheFunc() cache.NewCacheFunc {
+	managedBySelector := cache.ObjectSelector{
+		Label: labels.SelectorFromSet(labels.Set{v1alpha1.ManagedByLabel: v1alpha1.ManagedByLabelValue}),
+	}
+	selectors := cache.SelectorsByObject{
+		&appsv1.Deployment{}: managedBySelector,
+		&corev1.ConfigMap{}:  managedBySelector,
+	}
+
+	return func(config *rest.Config, options cache.Options) (cache.Cache, error) {
+		options.SelectorsByObject = selectors
+		if len(c.Namespaces) > 1 {
+			return cache.MultiNamespacedCacheBuilder(c.Namespaces)(config, options)
+		}
+
+		return cache.New(config, options)
+	}
+}
+
+// SetClusterNamespaces restricts the cache of a cluster to the watched namespaces. It can be passed as an option
+// when creating a cluster.
+func (c *OperatorConfig) SetClusterNamespaces(options *cluster.Options) {
+	if len(c.Namespaces) == 1 {
+		options.Namespace = c.Namespaces[0]
+	} else if len(c.Namespaces) > 1 {
+		options.NewCache = cache.MultiNamespacedCacheBuilder(c.Namespaces)
+	}
+}
+
+// validateLeaderElection returns the errors found in the leader election configuration.
+func (c *OperatorConfig) validateLeaderElection(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	leaderElection := c.LeaderElection
+	if leaderElection.Enabled && leaderElection.ID == "" {
+		errs = append(errs, field.Required(path.Child("id"), "required when the leader election is enabled"))
+	}
+
+	if leaderElection.RetryPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("retryPeriod"), leaderElection.RetryPeriod.Duration.String(),
+			"must be greater than 0"))
+	}
+	if leaderElection.RenewDeadline.Duration <= leaderElection.RetryPeriod.Duration {
+		errs = append(errs, field.Invalid(path.Child("renewDeadline"), leaderElection.RenewDeadline.Duration.String(),
+			"must be greater than retryPeriod"))
+	}
+	if leaderElection.LeaseDuration.Duration <= leaderElection.RenewDeadline.Duration {
+		errs = append(errs, field.Invalid(path.Child("leaseDuration"), leaderElection.LeaseDuration.Duration.String(),
+			"must be greater than renewDeadline"))
+	}
+
+	return errs
+}
+
+// validateSharding returns the errors found in the sharding configuration. Nothing is validated unless the sharded
+// mode is enabled.
+func (c *OperatorConfig) validateSharding(path *field.Path) field.ErrorList {
+	sharding := c.Sharding
+	if !sharding.Enabled {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if c.LeaderElection.Enabled {
+		errs = append(errs, field.Forbidden(path.Child("enabled"), "can't be enabled along with the leader election"))
+	}
+	if sharding.Buckets < 1 {
+		errs = append(errs, field.Invalid(path.Child("buckets"), sharding.Buckets, "must be greater than 0"))
+	}
+	if sharding.RenewPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("renewPeriod"), sharding.RenewPeriod.Duration.String(),
+			"must be greater than 0"))
+	}
+	if sharding.LeaseDuration.Duration <= sharding.RenewPeriod.Duration {
+		errs = append(errs, field.Invalid(path.Child("leaseDuration"), sharding.LeaseDuration.Duration.String(),
+			"must be greater than renewPeriod"))
+	}
+
+	return errs
+}
+
+// validateAddress returns an error if the given address is not in host:port form. "0" is accepted as it is used to
+// disable the endpoints.
+func validateAddress(path *field.Path, address string) field.ErrorList {
+	if address == "0" {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return field.ErrorList{field.Invalid(path, address, err.Error())}
+	}
+
+	return nil
+}
+
+// contains returns true if the given slice contains the given value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedKeys returns the keys of the given map sorted, so errors are always reported in the same order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
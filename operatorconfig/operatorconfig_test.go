@@ -0,0 +1,168 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OperatorConfig", func() {
+	// writeConfig writes the given content to a temporary configuration file, returning its path.
+	writeConfig := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	It("has a valid default configuration", func() {
+		Expect(DefaultConfig().Validate()).To(Succeed())
+	})
+
+	It("validates the sample configuration file", func() {
+		config, err := Load(filepath.Join("..", "config", "manager", "controller_manager_config.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("keeps the default values not set in the file", func() {
+		config, err := Load(writeConfig(`
+apiVersion: config.appstudio.redhat.com/v1alpha1
+kind: OperatorConfig
+webhook:
+  port: 9444
+controllers:
+  foo:
+    maxConcurrentReconciles: 4
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Webhook.Port).To(Equal(9444))
+		Expect(config.GetControllerConfig(FooController).MaxConcurrentReconciles).To(Equal(4))
+		Expect(config.GetControllerConfig(BarController).MaxConcurrentReconciles).To(BeZero())
+		Expect(config.Metrics.BindAddress).To(Equal(DefaultConfig().Metrics.BindAddress))
+		Expect(config.GetFinalizerName()).To(Equal("appstudio.redhat.com/finalizer"))
+		Expect(config.GetDrainFinalizerName()).To(Equal("appstudio.redhat.com/drain"))
+	})
+
+	It("rejects unknown fields", func() {
+		_, err := Load(writeConfig(`
+apiVersion: config.appstudio.redhat.com/v1alpha1
+kind: OperatorConfig
+webhooks:
+  port: 9444
+`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports all the invalid values", func() {
+		config, err := Load(writeConfig(`
+apiVersion: config.appstudio.redhat.com/v2
+kind: OperatorConfig
+controllers:
+  baz: {}
+  foo:
+    maxConcurrentReconciles: -1
+featureGates:
+  Unknown: true
+finalizationTimeout: -1m
+finalizerDomain: Not_A_Domain
+health:
+  reconcileProgressTimeout: -1m
+leaderElection:
+  leaseDuration: 5s
+maxReplicaStatuses: -1
+metrics:
+  bindAddress: 8080
+namespaces:
+- Invalid
+orphans:
+  policy: Ignore
+webhook:
+  port: 70000
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		err = config.Validate()
+		Expect(err).To(HaveOccurred())
+		for _, field := range []string{"apiVersion", "controllers", "controllers[foo].maxConcurrentReconciles",
+			"featureGates", "finalizationTimeout", "finalizerDomain", "health.reconcileProgressTimeout", "leaderElection.leaseDuration", "maxReplicaStatuses", "metrics.bindAddress", "namespaces[0]", "orphans.policy",
+			"webhook.port"} {
+			Expect(err.Error()).To(ContainSubstring(field + ":"))
+		}
+	})
+
+	It("validates the sharding configuration when enabled", func() {
+		config := DefaultConfig()
+		config.LeaderElection.Enabled = true
+		config.Sharding.Buckets = 0
+		Expect(config.Validate()).To(Succeed())
+
+		config.Sharding.Enabled = true
+		err := config.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("sharding.enabled: Forbidden"))
+		Expect(err.Error()).To(ContainSubstring("sharding.buckets: Invalid value"))
+
+		config.LeaderElection.Enabled = false
+		config.Sharding.Buckets = 16
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("enables the features unless disabled", func() {
+		config := DefaultConfig()
+		Expect(config.IsFeatureEnabled(WebhooksFeature)).To(BeTrue())
+
+		config.SetFeatureEnabled(WebhooksFeature, false)
+		Expect(config.IsFeatureEnabled(WebhooksFeature)).To(BeFalse())
+		Expect(config.IsFeatureEnabled(FooQuotasFeature)).To(BeTrue())
+	})
+
+	It("disables the opt-in features unless enabled", func() {
+		config := DefaultConfig()
+		Expect(config.IsFeatureEnabled(ReconcileTriggerFeature)).To(BeFalse())
+
+		config.SetFeatureEnabled(ReconcileTriggerFeature, true)
+		Expect(config.IsFeatureEnabled(ReconcileTriggerFeature)).To(BeTrue())
+	})
+
+	It("returns the matching manager options", func() {
+		config := DefaultConfig()
+		config.LeaderElection.Enabled = true
+		config.Namespaces = []string{"team-a"}
+
+		scheme := runtime.NewScheme()
+		options := config.GetManagerOptions(scheme)
+		Expect(options.Scheme).To(BeIdenticalTo(scheme))
+		Expect(options.Port).To(Equal(9443))
+		Expect(options.LeaderElection).To(BeTrue())
+		Expect(options.LeaderElectionID).To(Equal("c2d03d47.redhat.com"))
+		Expect(*options.LeaseDuration).To(Equal(15 * time.Second))
+		Expect(options.Namespace).To(Equal("team-a"))
+		Expect(options.NewCache).NotTo(BeNil())
+
+		config.Namespaces = append(config.Namespaces, "team-b")
+		options = config.GetManagerOptions(scheme)
+		Expect(options.Namespace).To(BeEmpty())
+		Expect(options.NewCache).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,456 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// BucketLabel is the label containing the bucket number of the bucket leases
+	BucketLabel = "appstudio.redhat.com/shard-bucket"
+
+	// MemberLabel is the label added to the member leases
+	MemberLabel = "appstudio.redhat.com/shard-member"
+
+	// GroupLabel is the label containing the name of the group of replicas the leases belong to
+	GroupLabel = "appstudio.redhat.com/shard-group"
+)
+
+type (
+	// Options are the options used to create a Sharder.
+	Options struct {
+		// Buckets is the number of hash buckets the Foo resources are distributed into
+		Buckets int
+
+		// Identity is the unique name of the replica
+		Identity string
+
+		// LeaseDuration is the duration a lease is held for without being renewed
+		LeaseDuration time.Duration
+
+		// Name is the name of the group of replicas sharing the buckets, used as prefix of the lease names
+		Name string
+
+		// Namespace is the namespace the leases are created in
+		Namespace string
+
+		// RenewPeriod is the duration between lease renewals and rebalances
+		RenewPeriod time.Duration
+	}
+
+	// Sharder distributes the Foo resources among the replicas of the operator. Every Foo belongs to a hash bucket and
+	// every bucket is held by one replica at most using a Lease. Replicas announce themselves with a member Lease and
+	// the buckets are spread evenly among the live members, so they are rebalanced when replicas join or leave. A nil
+	// Sharder owns every resource, so controllers can use it unconditionally.
+	Sharder struct {
+		clock       func() time.Time
+		client      client.Client
+		log         logr.Logger
+		mutex       sync.RWMutex
+		options     Options
+		owned       map[int]bool
+		reader      client.Reader
+		subscribers []chan event.GenericEvent
+	}
+
+	// Shardable is implemented by the controllers distributing their work among the replicas using a Sharder.
+	Shardable interface {
+		// SetSharder sets the Sharder used to tell which resources have to be reconciled by the replica.
+		SetSharder(sharder *Sharder)
+	}
+)
+
+// inClusterNamespacePath is the path of the file containing the namespace of the pod
+const inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// GetInClusterNamespace returns the namespace the operator is running in. It fails when running outside a cluster.
+func GetInClusterNamespace() (string, error) {
+	namespace, err := os.ReadFile(inClusterNamespacePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to get the namespace of the operator: %w", err)
+	}
+
+	return string(namespace), nil
+}
+
+// NewSharder creates a new Sharder writing the leases with the given client and reading them with the given reader,
+// which shouldn't be backed by a cache.
+func NewSharder(cli client.Client, reader client.Reader, options Options) *Sharder {
+	return &Sharder{
+		clock:   time.Now,
+		client:  cli,
+		log:     ctrl.Log.WithName("sharder").WithValues("identity", options.Identity),
+		options: options,
+		owned:   map[int]bool{},
+		reader:  reader,
+	}
+}
+
+// GetBucket returns the bucket of the given object. Foo and FooQuota resources are hashed by namespace and name, and
+// Bar resources by the namespace and name of their Foo, so both are reconciled by the same replica.
+func (s *Sharder) GetBucket(obj client.Object) int {
+	name := obj.GetName()
+	if bar, ok := obj.(*v1alpha1.Bar); ok && bar.Spec.Foo != "" {
+		name = bar.Spec.Foo
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(obj.GetNamespace() + "/" + name))
+
+	return int(hash.Sum32() % uint32(s.options.Buckets))
+}
+
+// GetOwnedBuckets returns the sorted buckets currently held by the replica.
+func (s *Sharder) GetOwnedBuckets() []int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	buckets := make([]int, 0, len(s.owned))
+	for bucket := range s.owned {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	return buckets
+}
+
+// InBucket returns true if the given object belongs to the bucket of the given bucket lease.
+func (s *Sharder) InBucket(obj client.Object, lease client.Object) bool {
+	bucket, err := strconv.Atoi(lease.GetLabels()[BucketLabel])
+	return err == nil && s.GetBucket(obj) == bucket
+}
+
+// Owns returns true if the given object belongs to a bucket held by the replica.
+func (s *Sharder) Owns(obj client.Object) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.owned[s.GetBucket(obj)]
+}
+
+// Predicate returns a predicate filtering out the Foo, Bar and FooQuota resources not owned by the replica. Other
+// objects, like the bucket leases sent by the Source, are not filtered.
+func (s *Sharder) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		switch obj.(type) {
+		case *v1alpha1.Foo, *v1alpha1.Bar, *v1alpha1.FooQuota:
+			return s.Owns(obj)
+		default:
+			return true
+		}
+	})
+}
+
+// Source returns a source receiving the bucket leases acquired by the replica, so controllers can reconcile the
+// resources in a bucket when taking it over. Every call returns a new source.
+func (s *Sharder) Source() source.Source {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	events := make(chan event.GenericEvent, s.options.Buckets)
+	s.subscribers = append(s.subscribers, events)
+
+	return &source.Channel{Source: events}
+}
+
+// NeedLeaderElection returns false, as every replica has to hold its own buckets.
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// Start rebalances and renews the leases periodically until the given context is done. The leases held by the
+// replica are released when stopping, so other replicas can take over its buckets without waiting for them to expire.
+func (s *Sharder) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.RenewPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sync(ctx); err != nil {
+			s.log.Error(err, "unable to sync the shard leases")
+		}
+
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), s.options.RenewPeriod)
+			defer cancel()
+
+			return s.release(releaseCtx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync renews the member lease of the replica, acquires the free buckets assigned to it, renews the buckets it
+// holds and releases the buckets assigned to other replicas.
+func (s *Sharder) sync(ctx context.Context) error {
+	now := s.clock()
+
+	members, err := s.syncMembers(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	leases, err := s.listLeases(ctx, BucketLabel)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for bucket := 0; bucket < s.options.Buckets; bucket++ {
+		lease := leases[s.getBucketLeaseName(bucket)]
+		held := lease != nil && s.isHeldBy(lease, s.options.Identity, now)
+		desired := members[bucket%len(members)] == s.options.Identity
+
+		var err error
+		switch {
+		case desired && held:
+			err = s.renew(ctx, lease, now)
+			s.setOwned(bucket, err == nil && s.isSettled(lease, now), lease)
+		case desired && s.isFree(lease, now):
+			lease, err = s.acquire(ctx, bucket, lease, now)
+			s.setOwned(bucket, err == nil && s.isSettled(lease, now), lease)
+		case held:
+			// The bucket is assigned to another replica, so it has to stop reconciling it before releasing the lease
+			s.setOwned(bucket, false, nil)
+			lease.Spec.HolderIdentity = nil
+			err = s.client.Update(ctx, lease)
+		default:
+			s.setOwned(bucket, false, nil)
+		}
+		if err != nil && !errors.IsConflict(err) && !errors.IsAlreadyExists(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// syncMembers renews the member lease of the replica, deletes the expired member leases and returns the sorted
+// identities of the live members.
+func (s *Sharder) syncMembers(ctx context.Context, now time.Time) ([]string, error) {
+	leases, err := s.listLeases(ctx, MemberLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []string{s.options.Identity}
+	for name, lease := range leases {
+		switch {
+		case name == s.getMemberLeaseName():
+			err = s.renew(ctx, lease, now)
+		case !s.isFree(lease, now):
+			members = append(members, *lease.Spec.HolderIdentity)
+		default:
+			err = client.IgnoreNotFound(s.client.Delete(ctx, lease))
+		}
+		if err != nil && !errors.IsConflict(err) {
+			return nil, err
+		}
+	}
+
+	if _, found := leases[s.getMemberLeaseName()]; !found {
+		lease := s.newLease(s.getMemberLeaseName(), MemberLabel, "true", now)
+		if err := s.client.Create(ctx, lease); err != nil && !errors.IsAlreadyExists(err) {
+			return nil, err
+		}
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// acquire takes the given bucket, creating its lease if it doesn't exist, and returns the lease. Taking over the lease
+// of another replica, or a released one, counts as a transition, so the replica waits for the bucket to settle before
+// reconciling it.
+func (s *Sharder) acquire(ctx context.Context, bucket int, lease *coordinationv1.Lease,
+	now time.Time) (*coordinationv1.Lease, error) {
+	if lease == nil {
+		lease = s.newLease(s.getBucketLeaseName(bucket), BucketLabel, strconv.Itoa(bucket), now)
+		return lease, s.client.Create(ctx, lease)
+	}
+
+	// A lease of the replica which expired can't have been held by another replica since
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != s.options.Identity {
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions += *lease.Spec.LeaseTransitions
+		}
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.HolderIdentity = &s.options.Identity
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+
+	return lease, s.client.Update(ctx, lease)
+}
+
+// release gives up all the leases held by the replica, deleting its member lease.
+func (s *Sharder) release(ctx context.Context) error {
+	for _, bucket := range s.GetOwnedBuckets() {
+		s.setOwned(bucket, false, nil)
+
+		lease := &coordinationv1.Lease{}
+		err := s.reader.Get(ctx, client.ObjectKey{Name: s.getBucketLeaseName(bucket), Namespace: s.options.Namespace}, lease)
+		if err != nil {
+			return err
+		}
+		lease.Spec.HolderIdentity = nil
+		if err := s.client.Update(ctx, lease); err != nil {
+			return err
+		}
+	}
+
+	member := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: s.getMemberLeaseName(), Namespace: s.options.Namespace},
+	}
+
+	return client.IgnoreNotFound(s.client.Delete(ctx, member))
+}
+
+// renew updates the renew time of the given lease.
+func (s *Sharder) renew(ctx context.Context, lease *coordinationv1.Lease, now time.Time) error {
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+	return s.client.Update(ctx, lease)
+}
+
+// setOwned adds or removes the given bucket from the buckets held by the replica. When a bucket is acquired, the
+// subscribers receive its lease, which is dropped if a subscriber is not consuming them.
+func (s *Sharder) setOwned(bucket int, owned bool, lease *coordinationv1.Lease) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !owned {
+		if s.owned[bucket] {
+			s.log.Info("released bucket", "bucket", bucket)
+			delete(s.owned, bucket)
+		}
+		return
+	}
+	if s.owned[bucket] {
+		return
+	}
+
+	s.log.Info("acquired bucket", "bucket", bucket)
+	s.owned[bucket] = true
+	for _, subscriber := range s.subscribers {
+		select {
+		case subscriber <- event.GenericEvent{Object: lease}:
+		default:
+			s.log.Info("subscriber not ready, dropping bucket event", "bucket", bucket)
+		}
+	}
+}
+
+// listLeases returns the leases of the group with the given label, keyed by name.
+func (s *Sharder) listLeases(ctx context.Context, label string) (map[string]*coordinationv1.Lease, error) {
+	leaseList := &coordinationv1.LeaseList{}
+	err := s.reader.List(ctx, leaseList, client.InNamespace(s.options.Namespace),
+		client.MatchingLabels{GroupLabel: s.options.Name}, client.HasLabels{label})
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make(map[string]*coordinationv1.Lease, len(leaseList.Items))
+	for i := range leaseList.Items {
+		leases[leaseList.Items[i].Name] = &leaseList.Items[i]
+	}
+
+	return leases, nil
+}
+
+// isFree returns true if the given lease doesn't exist, is not held or has expired.
+func (s *Sharder) isFree(lease *coordinationv1.Lease, now time.Time) bool {
+	return lease == nil || lease.Spec.HolderIdentity == nil || !s.isHeldBy(lease, *lease.Spec.HolderIdentity, now)
+}
+
+// isSettled returns true if the given bucket lease was created by the replica or was taken over from another replica
+// at least one lease duration ago. The previous holder might not have noticed it lost the bucket until its own lease
+// expired, or might still be finishing the reconciles it started before releasing it, so a replica waits that long
+// before reconciling the buckets it takes over.
+func (s *Sharder) isSettled(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.LeaseTransitions == nil || *lease.Spec.LeaseTransitions == 0 || lease.Spec.AcquireTime == nil {
+		return true
+	}
+
+	return !lease.Spec.AcquireTime.Add(s.options.LeaseDuration).After(now)
+}
+
+// isHeldBy returns true if the given lease is held by the given identity and hasn't expired.
+func (s *Sharder) isHeldBy(lease *coordinationv1.Lease, identity string, now time.Time) bool {
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != identity || lease.Spec.RenewTime == nil {
+		return false
+	}
+
+	return lease.Spec.RenewTime.Add(s.options.LeaseDuration).After(now)
+}
+
+// newLease returns a new lease held by the replica with the given name and label.
+func (s *Sharder) newLease(name, label, value string, now time.Time) *coordinationv1.Lease {
+	leaseDurationSeconds := int32(s.options.LeaseDuration.Seconds())
+
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.options.Namespace,
+			Labels: map[string]string{
+				GroupLabel: s.options.Name,
+				label:      value,
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			AcquireTime:          &metav1.MicroTime{Time: now},
+			HolderIdentity:       &s.options.Identity,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			RenewTime:            &metav1.MicroTime{Time: now},
+		},
+	}
+}
+
+// getBucketLeaseName returns the name of the lease of the given bucket.
+func (s *Sharder) getBucketLeaseName(bucket int) string {
+	return fmt.Sprintf("%s-bucket-%d", s.options.Name, bucket)
+}
+
+// getMemberLeaseName returns the name of the member lease of the replica.
+func (s *Sharder) getMemberLeaseName() string {
+	return fmt.Sprintf("%s-member-%s", s.options.Name, s.options.Identity)
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sharder", func() {
+	var (
+		ctx context.Context
+		cli client.Client
+		now time.Time
+	)
+
+	// newSharder returns a Sharder with the given identity using the shared fake client and clock.
+	newSharder := func(identity string) *Sharder {
+		sharder := NewSharder(cli, cli, Options{
+			Buckets:       4,
+			Identity:      identity,
+			LeaseDuration: 15 * time.Second,
+			Name:          "test",
+			Namespace:     "default",
+			RenewPeriod:   5 * time.Second,
+		})
+		sharder.clock = func() time.Time {
+			return now
+		}
+
+		return sharder
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cli = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		now = time.Now()
+	})
+
+	It("holds all the buckets when it is the only replica", func() {
+		sharder := newSharder("replica-a")
+		Expect(sharder.sync(ctx)).To(Succeed())
+
+		Expect(sharder.GetOwnedBuckets()).To(Equal([]int{0, 1, 2, 3}))
+
+		lease := &coordinationv1.Lease{}
+		Expect(cli.Get(ctx, client.ObjectKey{Name: "test-bucket-0", Namespace: "default"}, lease)).To(Succeed())
+		Expect(*lease.Spec.HolderIdentity).To(Equal("replica-a"))
+	})
+
+	It("rebalances the buckets when replicas join and leave", func() {
+		replicaA := newSharder("replica-a")
+		replicaB := newSharder("replica-b")
+		Expect(replicaA.sync(ctx)).To(Succeed())
+
+		// replica-b has to wait for replica-a to release its buckets
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaB.GetOwnedBuckets()).To(BeEmpty())
+
+		Expect(replicaA.sync(ctx)).To(Succeed())
+		Expect(replicaA.GetOwnedBuckets()).To(Equal([]int{0, 2}))
+
+		// replica-b takes over the released buckets, but only reconciles them one lease duration later
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaB.GetOwnedBuckets()).To(BeEmpty())
+		for i := 0; i < 3; i++ {
+			now = now.Add(5 * time.Second)
+			Expect(replicaA.sync(ctx)).To(Succeed())
+			Expect(replicaB.sync(ctx)).To(Succeed())
+		}
+		Expect(replicaA.GetOwnedBuckets()).To(Equal([]int{0, 2}))
+		Expect(replicaB.GetOwnedBuckets()).To(Equal([]int{1, 3}))
+
+		// replica-a stops renewing its leases, so they expire
+		now = now.Add(time.Minute)
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaB.GetOwnedBuckets()).To(Equal([]int{1, 3}))
+
+		now = now.Add(15 * time.Second)
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaB.GetOwnedBuckets()).To(Equal([]int{0, 1, 2, 3}))
+
+		leases := &coordinationv1.LeaseList{}
+		Expect(cli.List(ctx, leases, client.HasLabels{MemberLabel})).To(Succeed())
+		Expect(leases.Items).To(HaveLen(1))
+		Expect(leases.Items[0].Name).To(Equal("test-member-replica-b"))
+	})
+
+	It("releases its leases when stopping", func() {
+		replicaA := newSharder("replica-a")
+		replicaB := newSharder("replica-b")
+		Expect(replicaA.sync(ctx)).To(Succeed())
+		Expect(replicaB.sync(ctx)).To(Succeed())
+
+		Expect(replicaA.release(ctx)).To(Succeed())
+		Expect(replicaA.GetOwnedBuckets()).To(BeEmpty())
+
+		// replica-b takes over without waiting for the leases to expire, and reconciles the buckets once the
+		// reconciles replica-a started before releasing them are over
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaB.GetOwnedBuckets()).To(BeEmpty())
+
+		lease := &coordinationv1.Lease{}
+		Expect(cli.Get(ctx, client.ObjectKey{Name: "test-bucket-0", Namespace: "default"}, lease)).To(Succeed())
+		Expect(*lease.Spec.HolderIdentity).To(Equal("replica-b"))
+		Expect(*lease.Spec.LeaseTransitions).To(BeEquivalentTo(1))
+
+		now = now.Add(15 * time.Second)
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaB.GetOwnedBuckets()).To(Equal([]int{0, 1, 2, 3}))
+	})
+
+	It("sends the leases of the acquired buckets to the sources", func() {
+		sharder := newSharder("replica-a")
+		events := sharder.Source().(*source.Channel).Source
+
+		Expect(sharder.sync(ctx)).To(Succeed())
+		Expect(events).To(HaveLen(4))
+
+		// Renewing a bucket doesn't send it again
+		Expect(sharder.sync(ctx)).To(Succeed())
+		Expect(events).To(HaveLen(4))
+
+		var evt event.GenericEvent
+		Eventually(events).Should(Receive(&evt))
+		Expect(evt.Object.GetLabels()).To(HaveKeyWithValue(BucketLabel, "0"))
+	})
+
+	It("routes the Bar resources to the bucket of their Foo", func() {
+		sharder := newSharder("replica-a")
+		Expect(sharder.sync(ctx)).To(Succeed())
+
+		for i := 0; i < 10; i++ {
+			foo := &v1alpha1.Foo{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("foo-%d", i), Namespace: "default"}}
+			bar := &v1alpha1.Bar{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("bar-%d", i), Namespace: "default"},
+				Spec:       v1alpha1.BarSpec{Foo: foo.Name},
+			}
+			Expect(sharder.GetBucket(bar)).To(Equal(sharder.GetBucket(foo)))
+		}
+	})
+
+	It("filters out the resources in buckets held by other replicas", func() {
+		replicaA := newSharder("replica-a")
+		replicaB := newSharder("replica-b")
+		Expect(replicaA.sync(ctx)).To(Succeed())
+		Expect(replicaB.sync(ctx)).To(Succeed())
+		Expect(replicaA.sync(ctx)).To(Succeed())
+
+		foo := &v1alpha1.Foo{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+		owned := replicaA.GetBucket(foo)%2 == 0
+		Expect(replicaA.Owns(foo)).To(Equal(owned))
+		Expect(replicaA.Predicate().Generic(event.GenericEvent{Object: foo})).To(Equal(owned))
+		Expect(replicaA.Predicate().Generic(event.GenericEvent{Object: &coordinationv1.Lease{}})).To(BeTrue())
+
+		quota := &v1alpha1.FooQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "default"}}
+		Expect(replicaA.Predicate().Generic(event.GenericEvent{Object: quota})).To(Equal(replicaA.GetBucket(quota)%2 == 0))
+	})
+
+	It("owns every resource when nil", func() {
+		var sharder *Sharder
+		Expect(sharder.Owns(&v1alpha1.Foo{})).To(BeTrue())
+		Expect(sharder.Predicate().Generic(event.GenericEvent{Object: &v1alpha1.Foo{}})).To(BeTrue())
+	})
+})
@@ -0,0 +1,129 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"time"
+
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Foo lifecycle", Ordered, func() {
+	const (
+		namespace = "default"
+		timeout   = 10 * time.Second
+	)
+
+	var foo *v1alpha1.Foo
+
+	getBars := func() []v1alpha1.Bar {
+		bars := &v1alpha1.BarList{}
+		Expect(k8sClient.List(ctx, bars,
+			client.InNamespace(namespace),
+			client.MatchingLabels{v1alpha1.FooLabel: foo.Name})).To(Succeed())
+		return bars.Items
+	}
+
+	getHealthReason := func() string {
+		reconciledFoo := &v1alpha1.Foo{}
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(foo), reconciledFoo); err != nil {
+			return ""
+		}
+
+		condition := apimeta.FindStatusCondition(reconciledFoo.Status.Conditions, "Health")
+		if condition == nil {
+			return ""
+		}
+
+		return condition.Reason
+	}
+
+	setDesiredReplicas := func(replicas int) {
+		Eventually(func() error {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(foo), foo)).To(Succeed())
+			foo.Spec.DesiredReplicas = replicas
+			return k8sClient.Update(ctx, foo)
+		}, timeout).Should(Succeed())
+	}
+
+	BeforeAll(func() {
+		// Nothing drains the Bars in these tests, so they are deleted without waiting for them
+		var gracePeriod int64
+		foo = &v1alpha1.Foo{
+			ObjectMeta: metav1.ObjectMeta{Name: "lifecycle-foo", Namespace: namespace},
+			Spec:       v1alpha1.FooSpec{DesiredReplicas: 2, TerminationGracePeriodSeconds: &gracePeriod},
+		}
+		Expect(k8sClient.Create(ctx, foo)).To(Succeed())
+	})
+
+	It("creates the desired replicas", func() {
+		Eventually(getBars, timeout).Should(HaveLen(2))
+		Eventually(getHealthReason, timeout).Should(Equal(v1alpha1.HealthyReason.String()))
+
+		for _, bar := range getBars() {
+			Expect(bar.Spec.Foo).To(Equal(foo.Name))
+			Expect(metav1.GetControllerOf(&bar)).NotTo(BeNil())
+		}
+	})
+
+	It("scales up", func() {
+		setDesiredReplicas(4)
+		Eventually(getBars, timeout).Should(HaveLen(4))
+		Eventually(getHealthReason, timeout).Should(Equal(v1alpha1.HealthyReason.String()))
+	})
+
+	It("scales down", func() {
+		setDesiredReplicas(1)
+		Eventually(getBars, timeout).Should(HaveLen(1))
+		Eventually(getHealthReason, timeout).Should(Equal(v1alpha1.HealthyReason.String()))
+	})
+
+	It("recreates manually deleted replicas", func() {
+		bar := getBars()[0]
+		Expect(k8sClient.Delete(ctx, &bar)).To(Succeed())
+
+		Eventually(func() bool {
+			bars := getBars()
+			return len(bars) == 1 && bars[0].Name != bar.Name
+		}, timeout).Should(BeTrue())
+	})
+
+	It("rejects Bars referencing a missing Foo", func() {
+		err := k8sClient.Create(ctx, &v1alpha1.Bar{
+			ObjectMeta: metav1.ObjectMeta{Name: "lifecycle-orphan-bar", Namespace: namespace},
+			Spec:       v1alpha1.BarSpec{Foo: "missing-foo"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unexistent Foo resource"))
+	})
+
+	It("deletes the replicas along with the Foo", func() {
+		Expect(k8sClient.Delete(ctx, foo)).To(Succeed())
+
+		Eventually(getBars, timeout).Should(BeEmpty())
+		Eventually(func() bool {
+			return errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(foo), &v1alpha1.Foo{}))
+		}, timeout).Should(BeTrue())
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// tracingClient is a client.Client creating a span for every call.
+type tracingClient struct {
+	client.Client
+}
+
+// tracingStatusWriter is a client.StatusWriter creating a span for every call.
+type tracingStatusWriter struct {
+	client.StatusWriter
+	client tracingClient
+}
+
+// NewClient returns a client.Client that creates a span for every call made through the client passed as a parameter.
+// Spans are children of the span in the context passed to each call. Wrapping the same client twice returns equal
+// clients, so they can be used as map keys.
+func NewClient(cli client.Client) client.Client {
+	return tracingClient{Client: cli}
+}
+
+// Get traces the call to Get of the wrapped client.
+func (c tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, span := c.start(ctx, "Get", obj, key.Namespace, key.Name)
+	defer span.End()
+
+	err := c.Client.Get(ctx, key, obj, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// List traces the call to List of the wrapped client.
+func (c tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+
+	ctx, span := c.start(ctx, "List", list, listOptions.Namespace, "")
+	defer span.End()
+
+	err := c.Client.List(ctx, list, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// Create traces the call to Create of the wrapped client.
+func (c tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, span := c.start(ctx, "Create", obj, obj.GetNamespace(), obj.GetName())
+	defer span.End()
+
+	err := c.Client.Create(ctx, obj, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// Delete traces the call to Delete of the wrapped client.
+func (c tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, span := c.start(ctx, "Delete", obj, obj.GetNamespace(), obj.GetName())
+	defer span.End()
+
+	err := c.Client.Delete(ctx, obj, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// Update traces the call to Update of the wrapped client.
+func (c tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := c.start(ctx, "Update", obj, obj.GetNamespace(), obj.GetName())
+	defer span.End()
+
+	err := c.Client.Update(ctx, obj, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// Patch traces the call to Patch of the wrapped client.
+func (c tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := c.start(ctx, "Patch", obj, obj.GetNamespace(), obj.GetName())
+	defer span.End()
+
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// DeleteAllOf traces the call to DeleteAllOf of the wrapped client.
+func (c tracingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	deleteAllOfOptions := &client.DeleteAllOfOptions{}
+	deleteAllOfOptions.ApplyOptions(opts)
+
+	ctx, span := c.start(ctx, "DeleteAllOf", obj, deleteAllOfOptions.Namespace, "")
+	defer span.End()
+
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// Status returns a client.StatusWriter tracing the calls to the status subresource.
+func (c tracingClient) Status() client.StatusWriter {
+	return &tracingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+// Update traces the call to Update of the wrapped status writer.
+func (w *tracingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	ctx, span := w.client.start(ctx, "Status Update", obj, obj.GetNamespace(), obj.GetName())
+	defer span.End()
+
+	err := w.StatusWriter.Update(ctx, obj, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// Patch traces the call to Patch of the wrapped status writer.
+func (w *tracingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	ctx, span := w.client.start(ctx, "Status Patch", obj, obj.GetNamespace(), obj.GetName())
+	defer span.End()
+
+	err := w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	recordError(span, err)
+
+	return err
+}
+
+// start starts the span of a client call, returning the context containing it.
+func (c tracingClient) start(ctx context.Context, verb string, obj runtime.Object, namespace, name string) (context.Context, trace.Span) {
+	kind := fmt.Sprintf("%T", obj)
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+
+	attributes := []attribute.KeyValue{attribute.String("k8s.kind", kind)}
+	if namespace != "" {
+		attributes = append(attributes, attribute.String("k8s.namespace", namespace))
+	}
+	if name != "" {
+		attributes = append(attributes, attribute.String("k8s.name", name))
+	}
+
+	return tracer().Start(ctx, fmt.Sprintf("%s %s", verb, kind), trace.WithAttributes(attributes...))
+}
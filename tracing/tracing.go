@@ -0,0 +1,146 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ServiceName is the name the operator is identified with in the exported traces
+	ServiceName = "operator-toolkit-example"
+
+	// tracerName is the name of the tracer used to create the operator spans
+	tracerName = "github.com/konflux-ci/operator-toolkit-example"
+)
+
+// Setup configures the global tracer provider to export spans over OTLP/HTTP to the given endpoint, which has to be
+// in host:port form. The returned function flushes the pending spans and has to be called before exiting.
+func Setup(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// StartReconcile starts the span of a reconcile of the given kind of resource, returning the context containing it.
+// The span has to be ended by the caller.
+func StartReconcile(ctx context.Context, kind, namespace, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "Reconcile "+kind, trace.WithAttributes(
+		attribute.String("k8s.kind", kind),
+		attribute.String("k8s.namespace", namespace),
+		attribute.String("k8s.name", name),
+	))
+}
+
+// WithTraceID returns a logger containing the ID of the trace in the given context. The logger is returned unchanged
+// if the context doesn't contain a trace, which is the case when tracing is disabled.
+func WithTraceID(ctx context.Context, logger logr.Logger) logr.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return logger
+	}
+
+	return logger.WithValues("TraceID", spanContext.TraceID().String())
+}
+
+// TraceOperations wraps every operation in a span named after it. While an operation runs, the context the pointer
+// passed as a parameter points to is replaced by one containing the operation span, so the client calls made by the
+// operation are traced as its children.
+func TraceOperations(ctx *context.Context, operations ...controller.Operation) []controller.Operation {
+	traced := make([]controller.Operation, 0, len(operations))
+	for _, operation := range operations {
+		traced = append(traced, traceOperation(ctx, operation))
+	}
+
+	return traced
+}
+
+// traceOperation wraps the given operation in a span, recording the outcome of the operation in it.
+func traceOperation(ctx *context.Context, operation controller.Operation) controller.Operation {
+	name := operationName(operation)
+
+	return func() (controller.OperationResult, error) {
+		parent := *ctx
+		spanContext, span := tracer().Start(parent, name)
+		*ctx = spanContext
+		defer func() {
+			*ctx = parent
+			span.End()
+		}()
+
+		result, err := operation()
+		span.SetAttributes(
+			attribute.Bool("operation.cancel", result.CancelRequest),
+			attribute.Bool("operation.requeue", result.RequeueRequest),
+			attribute.String("operation.requeue_delay", result.RequeueDelay.String()),
+		)
+		recordError(span, err)
+
+		return result, err
+	}
+}
+
+// operationName returns the name of the method or function backing the given operation.
+func operationName(operation controller.Operation) string {
+	name := runtime.FuncForPC(reflect.ValueOf(operation).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// recordError marks the given span as failed if an error is passed.
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// tracer returns the tracer used to create the operator spans.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
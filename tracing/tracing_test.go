@@ -0,0 +1,133 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/konflux-ci/operator-toolkit-example/api/v1alpha1"
+	"github.com/konflux-ci/operator-toolkit-example/harness"
+	"github.com/konflux-ci/operator-toolkit/controller"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// operations holds operations to be traced, so they get a method name like the adapter ones.
+type operations struct {
+	client client.Client
+	ctx    context.Context
+	foo    *v1alpha1.Foo
+}
+
+func (o *operations) EnsureFooIsLoaded() (controller.OperationResult, error) {
+	return controller.RequeueOnErrorOrContinue(o.client.Get(o.ctx, client.ObjectKeyFromObject(o.foo), o.foo))
+}
+
+func (o *operations) EnsureFailure() (controller.OperationResult, error) {
+	return controller.RequeueWithError(fmt.Errorf("operation error"))
+}
+
+var _ = Describe("Tracing", func() {
+	var (
+		foo *v1alpha1.Foo
+		ops *operations
+	)
+
+	// findSpan returns the ended span with the given name.
+	findSpan := func(name string) tracetest.SpanStub {
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				return span
+			}
+		}
+		Fail("span not found: " + name)
+		return tracetest.SpanStub{}
+	}
+
+	BeforeEach(func() {
+		exporter.Reset()
+
+		foo = harness.NewFoo("foo", "default").Build()
+		h := harness.New(foo)
+		ops = &operations{client: NewClient(h.Client), foo: foo}
+	})
+
+	It("creates a span for every operation and client call within the reconcile span", func() {
+		ctx, span := StartReconcile(context.Background(), "Foo", foo.Namespace, foo.Name)
+		ops.ctx = ctx
+		_, err := controller.ReconcileHandler(TraceOperations(&ops.ctx, ops.EnsureFooIsLoaded))
+		span.End()
+		Expect(err).NotTo(HaveOccurred())
+
+		reconcileSpan := findSpan("Reconcile Foo")
+		operationSpan := findSpan("EnsureFooIsLoaded")
+		clientSpan := findSpan("Get Foo")
+		Expect(operationSpan.Parent.SpanID()).To(Equal(reconcileSpan.SpanContext.SpanID()))
+		Expect(clientSpan.Parent.SpanID()).To(Equal(operationSpan.SpanContext.SpanID()))
+		Expect(clientSpan.SpanContext.TraceID()).To(Equal(reconcileSpan.SpanContext.TraceID()))
+	})
+
+	It("restores the context after running an operation", func() {
+		ctx, span := StartReconcile(context.Background(), "Foo", foo.Namespace, foo.Name)
+		defer span.End()
+		ops.ctx = ctx
+
+		_, err := controller.ReconcileHandler(TraceOperations(&ops.ctx, ops.EnsureFooIsLoaded))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ops.ctx).To(Equal(ctx))
+	})
+
+	It("records the errors returned by the operations", func() {
+		ops.ctx = context.Background()
+		_, err := controller.ReconcileHandler(TraceOperations(&ops.ctx, ops.EnsureFailure))
+		Expect(err).To(HaveOccurred())
+
+		operationSpan := findSpan("EnsureFailure")
+		Expect(operationSpan.Status.Code).To(Equal(codes.Error))
+		Expect(operationSpan.Status.Description).To(Equal("operation error"))
+	})
+
+	It("adds the trace ID to the logger", func() {
+		var logged string
+		logger := funcr.New(func(_, args string) { logged = args }, funcr.Options{})
+
+		ctx, span := StartReconcile(context.Background(), "Foo", foo.Namespace, foo.Name)
+		defer span.End()
+		WithTraceID(ctx, logger).Info("message")
+
+		Expect(logged).To(ContainSubstring(span.SpanContext().TraceID().String()))
+	})
+
+	It("doesn't change the logger when there is no trace", func() {
+		var logged string
+		logger := funcr.New(func(_, args string) { logged = args }, funcr.Options{})
+
+		WithTraceID(context.Background(), logger).Info("message")
+		Expect(logged).NotTo(ContainSubstring("TraceID"))
+	})
+
+	It("returns equal clients when wrapping the same client", func() {
+		h := harness.New()
+		Expect(NewClient(h.Client)).To(Equal(NewClient(h.Client)))
+	})
+})